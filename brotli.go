@@ -0,0 +1,119 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// BrotliOptions sets quality 1..11 and window as log2 of its size, 10..24.
+// Zero means the default for both, as for a Codec level; quality 0 itself is not available.
+type BrotliOptions struct {
+	Quality int
+	Window  int
+}
+
+var DefaultBrotli = BrotliOptions{Quality: brotli.DefaultCompression}
+
+func Brotli(body []byte, opts ...BrotliOptions) ([]byte, error) {
+	var b bytes.Buffer
+
+	w := NewBrotliWriter(&b, opts...)
+	if _, err := w.Write(body); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func UnBrotli(b []byte) ([]byte, error) {
+	return io.ReadAll(brotli.NewReader(bytes.NewReader(b)))
+}
+
+// NewBrotliWriter compresses to w; Close flushes but leaves w open.
+func NewBrotliWriter(w io.Writer, opts ...BrotliOptions) io.WriteCloser {
+	o := DefaultBrotli
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Quality == 0 {
+		o.Quality = brotli.DefaultCompression
+	}
+	return brotli.NewWriterOptions(w, brotli.WriterOptions{Quality: o.Quality, LGWin: o.Window})
+}
+
+func NewBrotliReader(r io.Reader) io.Reader {
+	return brotli.NewReader(r)
+}
+
+// Precompress writes file.br and file.gz next to every file in dir of at least minSize bytes,
+// best quality, for servers that send precompressed assets.
+// Siblings newer than their file are kept; results not smaller than the file are dropped.
+func Precompress(dir string, minSize int64) error {
+	return filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(name, ".br") || strings.HasSuffix(name, ".gz") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < minSize {
+			return nil
+		}
+
+		if err := precompress(name, info, ".br", func(w io.Writer) io.WriteCloser {
+			return NewBrotliWriter(w, BrotliOptions{Quality: brotli.BestCompression})
+		}); err != nil {
+			return err
+		}
+		return precompress(name, info, ".gz", func(w io.Writer) io.WriteCloser {
+			gz, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+			return gz
+		})
+	})
+}
+
+func precompress(name string, info fs.FileInfo, ext string, writer func(io.Writer) io.WriteCloser) error {
+	to := name + ext
+	if s, err := os.Stat(to); err == nil && !s.ModTime().Before(info.ModTime()) {
+		return nil
+	}
+
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	err = writeFile(to, func(out io.Writer) error {
+		w := writer(out)
+		if _, err := io.Copy(w, in); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+	if err != nil {
+		return err
+	}
+
+	if Size(to) >= info.Size() {
+		return os.Remove(to)
+	}
+	return nil
+}
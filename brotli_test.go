@@ -0,0 +1,28 @@
+package file
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestBrotliDefaultQuality(u *testing.T) {
+	__(u)
+
+	body := []byte(strings.Repeat("zero quality is the default one, not the fastest. ", 2000))
+	zero, err := Brotli(body, BrotliOptions{Window: 22})
+	if err != nil {
+		u.Fatal(err)
+	}
+	def, _ := Brotli(body, BrotliOptions{Quality: brotli.DefaultCompression, Window: 22})
+	if !bytes.Equal(zero, def) {
+		u.Fatalf("quality 0 gave %d bytes, the default %d", len(zero), len(def))
+	}
+
+	back, err := UnBrotli(zero)
+	if err != nil || !bytes.Equal(back, body) {
+		u.Fatal("round trip", err)
+	}
+}
@@ -0,0 +1,275 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+type CassetteMode int
+
+const (
+	CassetteRecord CassetteMode = iota // hit the network and remember every exchange
+	CassetteReplay                     // serve remembered exchanges, never touch the network
+)
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"requestHeader,omitempty"`
+	RequestBody    []byte      `json:"requestBody,omitempty"`
+	StatusCode     int         `json:"statusCode"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	ResponseBody   []byte      `json:"responseBody,omitempty"`
+}
+
+// Cassette records HTTP exchanges of the package helpers to a file and replays them.
+// By default requests match by method, URL and the Range header, and credentials
+// are left out of the recording, see Redact.
+type Cassette struct {
+	sync.Mutex
+	filename string
+	mode     CassetteMode
+	method   bool
+	url      bool
+	body     bool
+	headers  []string
+	redact   []string
+	list     []*Interaction
+	used     map[int]bool
+}
+
+// RedactedHeaders are dropped from recorded requests and responses unless Redact says otherwise.
+var RedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// NewCassette loads filename in replay mode; in record mode the file is written by Save.
+func NewCassette(filename string, mode CassetteMode) (*Cassette, error) {
+	a := &Cassette{
+		filename: filename,
+		mode:     mode,
+		method:   true,
+		url:      true,
+		headers:  []string{"Range"},
+		redact:   RedactedHeaders,
+		used:     make(map[int]bool),
+	}
+	if mode == CassetteReplay {
+		if err := LoadJson(filename, &a.list); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Match sets what a replayed request must share with the recorded one.
+func (a *Cassette) Match(method, url, body bool, headers ...string) *Cassette {
+	a.Lock()
+	a.method, a.url, a.body, a.headers = method, url, body, headers
+	a.Unlock()
+	return a
+}
+
+// Redact sets the headers dropped from the recording; URL passwords are masked as long
+// as the list is not empty. Redact() with no names records everything as sent.
+// A redacted header can't be used by Match.
+func (a *Cassette) Redact(headers ...string) *Cassette {
+	a.Lock()
+	a.redact = headers
+	a.Unlock()
+	return a
+}
+
+func (a *Cassette) Interactions() []*Interaction {
+	a.Lock()
+	defer a.Unlock()
+	return append([]*Interaction(nil), a.list...)
+}
+
+func (a *Cassette) Save() error {
+	a.Lock()
+	body, err := json.MarshalIndent(a.list, "", "  ")
+	a.Unlock()
+	if err != nil {
+		return err
+	}
+	return Save(a.filename, body)
+}
+
+func (a *Cassette) matches(x *Interaction, req *http.Request, body []byte) bool {
+	if a.method && x.Method != req.Method {
+		return false
+	}
+	if a.url && x.URL != a.link(req) {
+		return false
+	}
+	if a.body && !bytes.Equal(x.RequestBody, body) {
+		return false
+	}
+	for _, h := range a.headers {
+		if x.RequestHeader.Get(h) != req.Header.Get(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// replay prefers interactions not served yet, so repeated requests play back in order.
+func (a *Cassette) replay(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Lock()
+	found := -1
+	for i, x := range a.list {
+		if !a.matches(x, req, body) {
+			continue
+		}
+		found = i
+		if !a.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		a.Unlock()
+		return nil, fmt.Errorf("cassette %s: no recorded response for %s %s", a.filename, req.Method, req.URL)
+	}
+	a.used[found] = true
+	x := a.list[found]
+	a.Unlock()
+
+	return x.response(req), nil
+}
+
+func (a *Cassette) record(req *http.Request, base http.RoundTripper) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	x := &Interaction{
+		Method:         req.Method,
+		URL:            a.link(req),
+		RequestHeader:  req.Header.Clone(),
+		RequestBody:    body,
+		StatusCode:     resp.StatusCode,
+		ResponseHeader: resp.Header.Clone(),
+		ResponseBody:   data,
+	}
+	a.list = append(a.list, x)
+
+	// the caller still gets the real headers, only the recording loses them
+	res := x.response(req)
+	for _, h := range a.redact {
+		x.RequestHeader.Del(h)
+		x.ResponseHeader.Del(h)
+	}
+	return res, nil
+}
+
+// link is the request URL as recorded, called with the lock held.
+func (a *Cassette) link(req *http.Request) string {
+	if len(a.redact) > 0 {
+		return req.URL.Redacted()
+	}
+	return req.URL.String()
+}
+
+func (x *Interaction) response(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:        strconv.Itoa(x.StatusCode) + " " + http.StatusText(x.StatusCode),
+		StatusCode:    x.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        x.ResponseHeader.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(x.ResponseBody)),
+		ContentLength: int64(len(x.ResponseBody)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	if req.Method == http.MethodHead {
+		resp.ContentLength = -1
+		if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+			resp.ContentLength = n
+		}
+	}
+	return resp
+}
+
+// readRequestBody reads the body and puts a fresh copy back on req.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+var (
+	cassetteMu sync.RWMutex
+	cassette   *Cassette
+)
+
+// UseCassette records or replays every HTTP helper of the package; nil goes back to the network.
+func UseCassette(c *Cassette) {
+	cassetteMu.Lock()
+	cassette = c
+	cassetteMu.Unlock()
+}
+
+func currentCassette() *Cassette {
+	cassetteMu.RLock()
+	defer cassetteMu.RUnlock()
+	return cassette
+}
+
+// recorder sits on top of the shared transports.
+type recorder struct {
+	base http.RoundTripper
+}
+
+func (a *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := currentCassette()
+	switch {
+	case c == nil:
+		return a.base.RoundTrip(req)
+	case c.mode == CassetteReplay:
+		return c.replay(req)
+	default:
+		return c.record(req, a.base)
+	}
+}
+
+func (a *recorder) CloseIdleConnections() {
+	if c, ok := a.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
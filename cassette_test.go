@@ -0,0 +1,81 @@
+package file
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCassetteGet sends credentials to link, returns the body and the Set-Cookie seen.
+func testCassetteGet(u *testing.T, link string) (string, string) {
+	c, err := httpclient()
+	if err != nil {
+		u.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, link, nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Cookie", "session=secret-cookie")
+	resp, err := c.Do(req)
+	if err != nil {
+		u.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.Header.Get("Set-Cookie")
+}
+
+func TestCassetteRedact(u *testing.T) {
+	__(u)
+	defer UseCassette(nil)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-set-cookie"})
+		io.WriteString(w, "hello")
+	})
+	srv := httptest.NewServer(handler)
+	link := strings.Replace(srv.URL, "http://", "http://user:secret-password@", 1) + "/a"
+
+	name := filepath.Join(u.TempDir(), "tape.json")
+	rec, _ := NewCassette(name, CassetteRecord)
+	UseCassette(rec)
+	// the caller still sees the real response
+	if body, cookie := testCassetteGet(u, link); body != "hello" || cookie == "" {
+		u.Fatalf("record: got %q %q", body, cookie)
+	}
+	if err := rec.Save(); err != nil {
+		u.Fatal(err)
+	}
+	tape, _ := os.ReadFile(name)
+	if strings.Contains(string(tape), "secret") {
+		u.Fatalf("credentials recorded: %s", tape)
+	}
+
+	play, err := NewCassette(name, CassetteReplay)
+	if err != nil {
+		u.Fatal(err)
+	}
+	UseCassette(play)
+	srv.Close()
+	if body, cookie := testCassetteGet(u, link); body != "hello" || cookie != "" {
+		u.Fatalf("replay: got %q %q", body, cookie)
+	}
+
+	// opting out records everything
+	rec, _ = NewCassette(name, CassetteRecord)
+	UseCassette(rec.Redact())
+	srv = httptest.NewServer(handler)
+	defer srv.Close()
+	link = strings.Replace(srv.URL, "http://", "http://user:secret-password@", 1) + "/a"
+	testCassetteGet(u, link)
+	rec.Save()
+	tape, _ = os.ReadFile(name)
+	for _, s := range []string{"secret-token", "secret-cookie", "secret-password", "secret-set-cookie"} {
+		if !strings.Contains(string(tape), s) {
+			u.Errorf("%s not recorded with Redact()", s)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// Codec is a streaming compression format.
+// Level 0 always means the codec's default; other levels use the codec's own scale.
+type Codec struct {
+	Name  string
+	Ext   string // file extension with the dot, ".gz"
+	Magic []byte // leading bytes of a stream, nil when the format can't be sniffed
+	// Writer is nil for formats that are only decoded
+	Writer func(w io.Writer, level int) (io.WriteCloser, error)
+	Reader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var codecs = struct {
+	sync.RWMutex
+	list []*Codec // registration order, sniffing goes through it
+	name map[string]*Codec
+}{name: make(map[string]*Codec)}
+
+func init() {
+	RegisterCodec(Codec{
+		Name:  "gzip",
+		Ext:   ".gz",
+		Magic: []byte{0x1f, 0x8b},
+		Writer: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = gzip.DefaultCompression
+			}
+			return gzip.NewWriterLevel(w, level)
+		},
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	})
+	RegisterCodec(Codec{
+		Name: "pgzip",
+		Ext:  ".gz",
+		Writer: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = pgzip.DefaultCompression
+			}
+			return pgzip.NewWriterLevel(w, level)
+		},
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			return pgzip.NewReader(r)
+		},
+	})
+	RegisterCodec(Codec{
+		Name:  "zstd",
+		Ext:   ".zst",
+		Magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		Writer: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				return zstd.NewWriter(w)
+			}
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		},
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	})
+	RegisterCodec(Codec{
+		Name:  "lz4",
+		Ext:   ".lz4",
+		Magic: []byte{0x04, 0x22, 0x4d, 0x18},
+		Writer: func(w io.Writer, level int) (io.WriteCloser, error) {
+			lw := lz4.NewWriter(w)
+			if level > 0 {
+				// 1..9 => lz4.Level1..lz4.Level9
+				if err := lw.Apply(lz4.CompressionLevelOption(lz4.Level1 << (min(level, 9) - 1))); err != nil {
+					return nil, err
+				}
+			}
+			return lw, nil
+		},
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(lz4.NewReader(r)), nil
+		},
+	})
+	RegisterCodec(Codec{
+		Name: "brotli",
+		Ext:  ".br",
+		Writer: func(w io.Writer, level int) (io.WriteCloser, error) {
+			if level == 0 {
+				level = brotli.DefaultCompression
+			}
+			return brotli.NewWriterLevel(w, level), nil
+		},
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(brotli.NewReader(r)), nil
+		},
+	})
+	RegisterCodec(Codec{
+		Name:  "bzip2",
+		Ext:   ".bz2",
+		Magic: []byte("BZh"),
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(bzip2.NewReader(r)), nil
+		},
+	})
+	RegisterCodec(Codec{
+		Name:  "xz",
+		Ext:   ".xz",
+		Magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		Writer: func(w io.Writer, level int) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		},
+		Reader: func(r io.Reader) (io.ReadCloser, error) {
+			xr, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(xr), nil
+		},
+	})
+	RegisterCodec(Codec{
+		Name:   "zip",
+		Ext:    ".zip",
+		Magic:  []byte("PK\x03\x04"),
+		Reader: zipEntryReader,
+	})
+}
+
+// RegisterCodec adds a codec or replaces the one with the same name.
+func RegisterCodec(c Codec) {
+	if c.Name == "" || c.Reader == nil {
+		panic("file: codec needs a name and a reader")
+	}
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	if old, ok := codecs.name[c.Name]; ok {
+		*old = c
+		return
+	}
+	codecs.name[c.Name] = &c
+	codecs.list = append(codecs.list, &c)
+}
+
+// GetCodec returns the registered codec by name.
+func GetCodec(name string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	c, ok := codecs.name[name]
+	if !ok {
+		return Codec{}, false
+	}
+	return *c, true
+}
+
+// NewCompressWriter compresses everything written to it into w with the named codec.
+// Close flushes the codec but does not close w.
+func NewCompressWriter(w io.Writer, codec string, level int) (io.WriteCloser, error) {
+	c, ok := GetCodec(codec)
+	if !ok {
+		return nil, fmt.Errorf("compress: unknown codec %q", codec)
+	}
+	if c.Writer == nil {
+		return nil, fmt.Errorf("compress: %s can only be decompressed", codec)
+	}
+	return c.Writer(w, level)
+}
+
+// NewDecompressReader recognizes the codec of r by its magic bytes and decodes it.
+// Unrecognized input returns *UnknownFormatError, brotli has no magic bytes
+// and needs NewDecompressReaderCodec.
+func NewDecompressReader(r io.Reader) (io.ReadCloser, error) {
+	return decompressReader(r, "")
+}
+
+// NewDecompressReaderCodec decodes r with the named codec, nothing is sniffed.
+func NewDecompressReaderCodec(r io.Reader, codec string) (io.ReadCloser, error) {
+	c, ok := GetCodec(codec)
+	if !ok {
+		return nil, fmt.Errorf("compress: unknown codec %q", codec)
+	}
+	return c.Reader(r)
+}
+
+// decompressReader sniffs r and falls back to the codec of the file extension ext.
+func decompressReader(r io.Reader, ext string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(16)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	c, ok := sniffCodec(head)
+	if !ok && ext != "" {
+		c, ok = codecByExt(ext)
+	}
+	if !ok {
+		return nil, &UnknownFormatError{Head: append([]byte(nil), head...)}
+	}
+	return c.Reader(br)
+}
+
+func sniffCodec(head []byte) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	for _, c := range codecs.list {
+		if len(c.Magic) > 0 && bytes.HasPrefix(head, c.Magic) {
+			return *c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// codecByExt returns the first codec registered for ext, gzip rather than pgzip for .gz.
+func codecByExt(ext string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	for _, c := range codecs.list {
+		if c.Ext == ext {
+			return *c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// CompressFile writes src compressed with codec to dst.
+func CompressFile(src, dst, codec string, level int) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return writeFile(dst, func(out io.Writer) error {
+		w, err := NewCompressWriter(out, codec, level)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(w, in); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// DecompressFile writes src decoded to dst with the given codec, or the one recognized
+// by magic bytes, or else the one of the src extension, so x.br works too.
+func DecompressFile(src, dst string, codec ...string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.ReadCloser
+	if len(codec) > 0 && codec[0] != "" {
+		r, err = NewDecompressReaderCodec(in, codec[0])
+	} else {
+		r, err = decompressReader(in, filepath.Ext(src))
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return writeFile(dst, func(out io.Writer) error {
+		_, err := io.Copy(out, r)
+		return err
+	})
+}
+
+// writeFile creates filename and removes it again if write fails.
+func writeFile(filename string, write func(io.Writer) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(filename)
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package file
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writableCodecs lists the registered codecs that can compress.
+func writableCodecs() []Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	var list []Codec
+	for _, c := range codecs.list {
+		if c.Writer != nil {
+			list = append(list, *c)
+		}
+	}
+	return list
+}
+
+func TestCodecRoundTrip(u *testing.T) {
+	__(u)
+
+	body := []byte(strings.Repeat("round trip through every codec\n", 1000))
+	dir := u.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	os.WriteFile(src, body, 0o644)
+
+	for _, c := range writableCodecs() {
+		for _, level := range []int{0, 1} {
+			dst := filepath.Join(dir, c.Name+c.Ext)
+			if err := CompressFile(src, dst, c.Name, level); err != nil {
+				u.Fatalf("%s %d: %v", c.Name, level, err)
+			}
+
+			// by name, then by magic bytes or extension
+			for _, codec := range []string{c.Name, ""} {
+				out := filepath.Join(dir, "out")
+				if err := DecompressFile(dst, out, codec); err != nil {
+					u.Fatalf("%s %d %q: %v", c.Name, level, codec, err)
+				}
+				if b, _ := os.ReadFile(out); !bytes.Equal(b, body) {
+					u.Fatalf("%s %d %q: body differs", c.Name, level, codec)
+				}
+			}
+
+			var b bytes.Buffer
+			w, _ := NewCompressWriter(&b, c.Name, level)
+			w.Write(body)
+			w.Close()
+			r, err := NewDecompressReaderCodec(&b, c.Name)
+			if err != nil {
+				u.Fatalf("%s reader: %v", c.Name, err)
+			}
+			got, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || !bytes.Equal(got, body) {
+				u.Fatalf("%s reader: %v", c.Name, err)
+			}
+		}
+	}
+}
+
+func TestDecompressReaderUnknown(u *testing.T) {
+	__(u)
+
+	b, _ := Brotli([]byte("no magic"))
+	if _, err := NewDecompressReader(bytes.NewReader(b)); err == nil {
+		u.Fatal("brotli sniffed")
+	}
+	if _, err := NewDecompressReaderCodec(bytes.NewReader(b), "nope"); err == nil {
+		u.Fatal("unknown codec accepted")
+	}
+}
@@ -3,7 +3,6 @@ package file
 import (
 	"archive/zip"
 	"bytes"
-	"compress/gzip"
 	"errors"
 	"io"
 	"os"
@@ -14,8 +13,16 @@ import (
 	"github.com/shamaton/msgpack/v3"
 )
 
-func Uncompress(b []byte, n any) error {
-	r, err := gzip.NewReader(bytes.NewReader(b))
+// Uncompress decodes what Compress made, recognizing the codec by its magic bytes
+// unless one is given, brotli has none and has to be named.
+func Uncompress(b []byte, n any, codec ...string) error {
+	var r io.ReadCloser
+	var err error
+	if len(codec) > 0 && codec[0] != "" {
+		r, err = NewDecompressReaderCodec(bytes.NewReader(b), codec[0])
+	} else {
+		r, err = NewDecompressReader(bytes.NewReader(b))
+	}
 	if err != nil {
 		return err
 	}
@@ -29,7 +36,9 @@ func Uncompress(b []byte, n any) error {
 	return msgpack.Unmarshal(body, n)
 }
 
-func Compress(v any) ([]byte, error) {
+// Compress packs v with msgpack and gzip, or with the given registered codec, e.g. "zstd".
+// Brotli output has no magic bytes, pass "brotli" to Uncompress as well.
+func Compress(v any, codec ...string) ([]byte, error) {
 	body, err := msgpack.Marshal(v)
 	if err != nil {
 		return nil, err
@@ -37,7 +46,14 @@ func Compress(v any) ([]byte, error) {
 
 	var b bytes.Buffer
 
-	gz := gzip.NewWriter(&b)
+	name := "gzip"
+	if len(codec) > 0 && codec[0] != "" {
+		name = codec[0]
+	}
+	gz, err := NewCompressWriter(&b, name, 0)
+	if err != nil {
+		return nil, err
+	}
 	if _, err := gz.Write(body); err != nil {
 		_ = gz.Close()
 		return nil, err
@@ -0,0 +1,34 @@
+package file
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompressCodecs(u *testing.T) {
+	__(u)
+
+	v := map[string][]int{"a": {1, 2, 3}, "b": {4}}
+	for _, c := range append(writableCodecs(), Codec{Name: ""}) {
+		b, err := Compress(v, c.Name)
+		if err != nil {
+			u.Fatalf("%q: %v", c.Name, err)
+		}
+
+		var got map[string][]int
+		if err := Uncompress(b, &got, c.Name); err != nil || !reflect.DeepEqual(got, v) {
+			u.Fatalf("%q named: %v %v", c.Name, err, got)
+		}
+		if c.Name == "brotli" {
+			continue // nothing to sniff
+		}
+		got = nil
+		if err := Uncompress(b, &got); err != nil || !reflect.DeepEqual(got, v) {
+			u.Fatalf("%q sniffed: %v %v", c.Name, err, got)
+		}
+	}
+
+	if _, err := Compress(v, "nope"); err == nil {
+		u.Fatal("unknown codec accepted")
+	}
+}
@@ -0,0 +1,325 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Cookie is a stored cookie with the attributes the jar needs to send it back.
+type Cookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires"` // zero for session cookies
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+	HostOnly bool      `json:"hostOnly,omitempty"` // sent to Domain only, not to subdomains
+}
+
+func (c *Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c *Cookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+// CookieJar is an http.CookieJar that can be saved to and loaded from files.
+type CookieJar struct {
+	sync.Mutex
+	cookies map[string]*Cookie
+}
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[string]*Cookie)}
+}
+
+func (a *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := canonicalHost(u.Host)
+	now := time.Now()
+
+	a.Lock()
+	defer a.Unlock()
+
+	for _, hc := range cookies {
+		c := &Cookie{
+			Name:     hc.Name,
+			Value:    hc.Value,
+			Path:     hc.Path,
+			Secure:   hc.Secure,
+			HttpOnly: hc.HttpOnly,
+		}
+
+		if d := strings.TrimPrefix(strings.ToLower(hc.Domain), "."); d == "" || d == host {
+			// a site living on a public suffix itself keeps its cookies to itself
+			c.Domain, c.HostOnly = host, d == "" || publicSuffix(d)
+		} else if domainMatch(host, d) && !publicSuffix(d) {
+			c.Domain = d
+		} else {
+			continue
+		}
+
+		if c.Path == "" || c.Path[0] != '/' {
+			c.Path = defaultPath(u.Path)
+		}
+
+		switch {
+		case hc.MaxAge < 0:
+			delete(a.cookies, c.key())
+			continue
+		case hc.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+		case !hc.Expires.IsZero():
+			c.Expires = hc.Expires
+		}
+
+		if c.expired(now) {
+			delete(a.cookies, c.key())
+			continue
+		}
+		a.cookies[c.key()] = c
+	}
+}
+
+func (a *CookieJar) Cookies(u *url.URL) (res []*http.Cookie) {
+	host := canonicalHost(u.Host)
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	now := time.Now()
+
+	a.Lock()
+	var list []*Cookie
+	for k, c := range a.cookies {
+		if c.expired(now) {
+			delete(a.cookies, k)
+			continue
+		}
+		if c.Secure && !secure {
+			continue
+		}
+		if c.HostOnly && c.Domain != host || !c.HostOnly && !domainMatch(host, c.Domain) {
+			continue
+		}
+		if !pathMatch(p, c.Path) {
+			continue
+		}
+		list = append(list, c)
+	}
+	a.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return len(list[i].Path) > len(list[j].Path)
+	})
+	for _, c := range list {
+		res = append(res, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return
+}
+
+// All returns the cookies that have not expired yet.
+func (a *CookieJar) All() (res []Cookie) {
+	now := time.Now()
+	a.Lock()
+	defer a.Unlock()
+	for _, c := range a.cookies {
+		if !c.expired(now) {
+			res = append(res, *c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].key() < res[j].key()
+	})
+	return
+}
+
+func (a *CookieJar) Add(list ...Cookie) {
+	a.Lock()
+	defer a.Unlock()
+	for _, c := range list {
+		c := c
+		c.Domain = strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		a.cookies[c.key()] = &c
+	}
+}
+
+func (a *CookieJar) Clear() {
+	a.Lock()
+	a.cookies = make(map[string]*Cookie)
+	a.Unlock()
+}
+
+// Save writes the jar as JSON, session cookies included.
+func (a *CookieJar) Save(filename string) error {
+	body, err := json.MarshalIndent(a.All(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return Save(filename, body)
+}
+
+func (a *CookieJar) Load(filename string) error {
+	var list []Cookie
+	if err := LoadJson(filename, &list); err != nil {
+		return err
+	}
+	a.Add(list...)
+	return nil
+}
+
+// SaveNetscape writes the cookies.txt format of curl and browser extensions.
+func (a *CookieJar) SaveNetscape(filename string) error {
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, c := range a.All() {
+		domain := c.Domain
+		if c.HttpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!c.HostOnly), c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+	}
+	return Save(filename, []byte(b.String()))
+}
+
+func (a *CookieJar) LoadNetscape(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var list []Cookie
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		if httpOnly {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		p := strings.Split(line, "\t")
+		if len(p) != 7 {
+			return fmt.Errorf("%s:%d: expected 7 fields, got %d", filename, n, len(p))
+		}
+		expires, err := strconv.ParseInt(p[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", filename, n, err)
+		}
+
+		c := Cookie{
+			Domain:   p[0],
+			HostOnly: !strings.EqualFold(p[1], "TRUE"),
+			Path:     p[2],
+			Secure:   strings.EqualFold(p[3], "TRUE"),
+			Name:     p[5],
+			Value:    p[6],
+			HttpOnly: httpOnly,
+		}
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+		list = append(list, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	a.Add(list...)
+	return nil
+}
+
+func netscapeBool(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func canonicalHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// publicSuffix reports whether cookies for domain would reach unrelated sites,
+// like com or co.uk, the same check net/http/cookiejar does.
+func publicSuffix(domain string) bool {
+	if net.ParseIP(domain) != nil {
+		return false
+	}
+	_, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	return err != nil
+}
+
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+func pathMatch(p, cookiePath string) bool {
+	if p == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(p, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || p[len(cookiePath)] == '/'
+}
+
+// defaultPath is the directory of the request path, RFC 6265 5.1.4.
+func defaultPath(p string) string {
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+var (
+	jarMu sync.RWMutex
+	jar   *CookieJar
+)
+
+// UseCookies shares jar between Get, Post, Redirect, DownloadFile and Downloader.
+// nil disables cookies.
+func UseCookies(j *CookieJar) {
+	jarMu.Lock()
+	jar = j
+	jarMu.Unlock()
+}
+
+// currentJar never returns a typed nil, http.Client would call it.
+func currentJar() http.CookieJar {
+	jarMu.RLock()
+	defer jarMu.RUnlock()
+	if jar == nil {
+		return nil
+	}
+	return jar
+}
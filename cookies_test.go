@@ -0,0 +1,40 @@
+package file
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCookieJarDomain(u *testing.T) {
+	__(u)
+
+	cases := []struct {
+		host, domain string
+		to           []string // hosts that get the cookie back
+		not          []string
+	}{
+		{"www.example.com", "example.com", []string{"example.com", "a.example.com"}, []string{"example.org"}},
+		{"www.example.com", "", []string{"www.example.com"}, []string{"a.www.example.com", "example.com"}},
+		{"www.example.com", "com", nil, []string{"www.example.com", "other.com"}},
+		{"shop.example.co.uk", "co.uk", nil, []string{"shop.example.co.uk", "evil.co.uk"}},
+		{"user.github.io", "github.io", nil, []string{"user.github.io", "other.github.io"}},
+		{"user.github.io", "user.github.io", []string{"user.github.io", "a.user.github.io"}, []string{"other.github.io"}},
+		{"co.uk", "co.uk", []string{"co.uk"}, []string{"evil.co.uk"}},
+		{"www.example.com", "other.com", nil, []string{"other.com", "www.example.com"}},
+	}
+	for _, c := range cases {
+		jar := NewCookieJar()
+		jar.SetCookies(&url.URL{Scheme: "https", Host: c.host, Path: "/"}, []*http.Cookie{{Name: "id", Value: "1", Domain: c.domain}})
+		for _, h := range c.to {
+			if len(jar.Cookies(&url.URL{Scheme: "https", Host: h, Path: "/"})) != 1 {
+				u.Errorf("%s Domain=%q: not sent to %s", c.host, c.domain, h)
+			}
+		}
+		for _, h := range c.not {
+			if len(jar.Cookies(&url.URL{Scheme: "https", Host: h, Path: "/"})) != 0 {
+				u.Errorf("%s Domain=%q: sent to %s", c.host, c.domain, h)
+			}
+		}
+	}
+}
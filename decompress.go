@@ -0,0 +1,58 @@
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// UnknownFormatError is returned for data no registered codec recognizes.
+type UnknownFormatError struct {
+	Head []byte // first bytes of the input
+}
+
+func (e *UnknownFormatError) Error() string {
+	return fmt.Sprintf("compress: unknown format, starts with % x", e.Head)
+}
+
+// Decompress decodes gzip, zstd, lz4 frame, bzip2, xz or a single-file zip, recognized by magic bytes.
+// Output of LZ4 is a bare block without a magic and is not recognized, use UnLZ4.
+func Decompress(b []byte) ([]byte, error) {
+	r, err := NewDecompressReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// DetectCodec names the codec of data starting with head, "" when unknown.
+func DetectCodec(head []byte) string {
+	c, _ := sniffCodec(head)
+	return c.Name
+}
+
+// zipEntryReader reads the only file of a zip archive.
+func zipEntryReader(r io.Reader) (io.ReadCloser, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*zip.File
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, f)
+		}
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("compress: zip holds %d files, use Unzip", len(files))
+	}
+	return files[0].Open()
+}
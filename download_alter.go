@@ -1,44 +1,49 @@
 package file
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/cavaliergopher/grab/v3"
-	"github.com/monopolly/useragent"
 )
 
-/*  */
+/* from: http(s), file:// или data: */
 func DownloadFile(from string, to string, headers map[string]string, progress ...func(now, total, percent int)) (err error) {
 
-	ua := useragent.Generate()
 	p := func(now, total, percent int) {}
+	if len(progress) > 0 {
+		p = progress[0]
+	}
+
+	if ok, err := copyLocal(from, to); ok {
+		if err == nil {
+			size := Int(to)
+			p(size, size, 100)
+		}
+		return err
+	}
+
 	req, err := grab.NewRequest(to, from)
 	if err != nil {
 		return
 	}
 
-	if headers != nil {
-		for k, v := range headers {
-			req.HTTPRequest.Header.Add(k, v)
-		}
-
-		if userAgent := headers["User-agent"]; userAgent != "" {
-			ua = userAgent
-		}
+	for k, v := range headers {
+		req.HTTPRequest.Header.Add(k, v)
 	}
+	currentProfile().apply(req.HTTPRequest.Header)
 
-	req.HTTPRequest.Header.Add("Connection", "keep-alive")
-	req.HTTPRequest.Header.Add("Accept", "*/*")
-	req.HTTPRequest.Header.Add("Accept-Language", "en-us")
-	req.HTTPRequest.Header.Add("DNT", "1")
+	rt, err := transport()
+	if err != nil {
+		return
+	}
 
 	c := grab.NewClient()
-	c.UserAgent = ua
-	resp := c.Do(req)
-
-	if len(progress) > 0 {
-		p = progress[0]
+	c.HTTPClient = &retrier{
+		client: &http.Client{Transport: rt, Jar: currentJar()},
+		policy: currentRetry(),
 	}
+	resp := c.Do(req)
 
 	t := time.NewTicker(500 * time.Millisecond)
 	defer t.Stop()
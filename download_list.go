@@ -0,0 +1,284 @@
+package file
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ListOptions configures DownloadList.
+type ListOptions struct {
+	Parallel int    // downloads at once, default 4
+	CSV      bool   // list lines are url,name,checksum instead of whitespace separated
+	Delim    byte   // CSV delimiter, default ','
+	Report   string // CSV report file: url, file, status, bytes, error
+	Headers  map[string]string
+}
+
+// ListResult is the outcome of one line of the list.
+type ListResult struct {
+	URL    string
+	File   string
+	Status string // downloaded, skipped, failed
+	Size   int64
+	Err    error
+}
+
+type listItem struct {
+	url  string
+	name string
+	sum  string
+}
+
+// DownloadList downloads every url from listFile into destDir.
+// A line is "url [name] [checksum]", checksum as hex or algo:hex (md5, sha1, sha256, sha512).
+// Files already in destDir are skipped when their checksum matches or none is given;
+// downloads go to name.part first, so only complete files ever get the final name.
+// Repeated names are numbered: a.txt, a-1.txt.
+func DownloadList(listFile, destDir string, opts ...ListOptions) (res []ListResult, err error) {
+	var o ListOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Parallel <= 0 {
+		o.Parallel = 4
+	}
+	if o.Delim == 0 {
+		o.Delim = ','
+	}
+
+	items, err := readList(listFile, o)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(destDir, 0o777); err != nil {
+		return nil, err
+	}
+
+	files := listFiles(items, destDir)
+
+	res = make([]ListResult, len(items))
+	sem := make(chan struct{}, o.Parallel)
+	wg := &sync.WaitGroup{}
+	var logMu sync.Mutex
+
+	for i, x := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, x listItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			r := downloadListItem(x, files[i], o.Headers)
+			res[i] = r
+
+			if o.Report != "" {
+				var msg string
+				if r.Err != nil {
+					msg = r.Err.Error()
+				}
+				logMu.Lock()
+				_ = Log(o.Report, r.URL, r.File, r.Status, r.Size, msg)
+				logMu.Unlock()
+			}
+		}(i, x)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range res {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.URL, r.Err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+func readList(listFile string, o ListOptions) (items []listItem, err error) {
+	add := func(p []string) {
+		if len(p) == 0 || p[0] == "" || strings.HasPrefix(p[0], "#") {
+			return
+		}
+		x := listItem{url: p[0]}
+		if len(p) > 1 {
+			x.name = p[1]
+		}
+		if len(p) > 2 {
+			x.sum = p[2]
+		}
+		items = append(items, x)
+	}
+
+	if o.CSV {
+		f, err := os.Open(listFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r := csv.NewReader(f)
+		r.Comma = rune(o.Delim)
+		r.Comment = '#'
+		r.FieldsPerRecord = -1 // name and checksum are optional
+		r.TrimLeadingSpace = true
+		for {
+			line, err := r.Read()
+			if err == io.EOF {
+				return items, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			add(line)
+		}
+	}
+
+	err = PlayBytes(listFile, func(line []byte) {
+		add(strings.Fields(string(line)))
+	})
+	return
+}
+
+// listFiles picks a file in destDir for every item, numbering repeated names: a.txt, a-1.txt.
+func listFiles(items []listItem, destDir string) []string {
+	files := make([]string, len(items))
+	used := make(map[string]bool)
+	for i, x := range items {
+		name := listBase(x.name)
+		if name == "" {
+			name = listBase(listName(x.url))
+		}
+		if name == "" {
+			name = "index"
+		}
+
+		ext := filepath.Ext(name)
+		unique := name
+		for n := 1; used[unique]; n++ {
+			unique = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), n, ext)
+		}
+		used[unique] = true
+		files[i] = filepath.Join(destDir, unique)
+	}
+	return files
+}
+
+// listBase is the last element of name, "" for names like "..", "." or "/"
+// that would make destDir itself the target.
+func listBase(name string) string {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if name == string(filepath.Separator) || name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// downloadListItem downloads into file.part and renames it when complete and verified,
+// so a file already in place is never a leftover of an interrupted run.
+func downloadListItem(x listItem, file string, headers map[string]string) (r ListResult) {
+	r.URL = x.url
+	r.File = file
+
+	if Exists(r.File) {
+		ok, err := verifySum(r.File, x.sum)
+		if err != nil {
+			r.Status, r.Err = "failed", err
+			return
+		}
+		if ok {
+			r.Status, r.Size = "skipped", Size(r.File)
+			return
+		}
+		_ = os.Remove(r.File)
+	}
+
+	part := r.File + ".part"
+	_ = os.Remove(part)
+	if err := DownloadFile(x.url, part, headers); err != nil {
+		_ = os.Remove(part)
+		r.Status, r.Err = "failed", err
+		return
+	}
+
+	ok, err := verifySum(part, x.sum)
+	if err == nil && !ok {
+		err = errors.New("checksum mismatch")
+	}
+	if err == nil {
+		err = os.Rename(part, r.File)
+	}
+	if err != nil {
+		_ = os.Remove(part)
+		r.Status, r.Err = "failed", err
+		return
+	}
+
+	r.Status, r.Size = "downloaded", Size(r.File)
+	return
+}
+
+// listName takes the last path element of link, or its host.
+func listName(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return filepath.Base(link)
+	}
+	if name := path.Base(u.Path); name != "/" && name != "." && name != "" {
+		return name
+	}
+	if u.Host != "" {
+		return u.Host
+	}
+	return "index"
+}
+
+// verifySum reports whether filename matches sum; an empty sum always matches.
+func verifySum(filename, sum string) (bool, error) {
+	if sum == "" {
+		return true, nil
+	}
+
+	algo, want, ok := strings.Cut(sum, ":")
+	if !ok {
+		algo, want = "", sum
+	}
+	want = strings.ToLower(want)
+
+	var h hash.Hash
+	switch {
+	case algo == "md5" || algo == "" && len(want) == 32:
+		h = md5.New()
+	case algo == "sha1" || algo == "" && len(want) == 40:
+		h = sha1.New()
+	case algo == "sha256" || algo == "" && len(want) == 64:
+		h = sha256.New()
+	case algo == "sha512" || algo == "" && len(want) == 128:
+		h = sha512.New()
+	default:
+		return false, fmt.Errorf("unknown checksum %q", sum)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)) == want, nil
+}
@@ -0,0 +1,131 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testListServer(u *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "body of "+r.URL.Path)
+	}))
+	u.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownloadListCSV(u *testing.T) {
+	__(u)
+
+	srv := testListServer(u)
+	dir := u.TempDir()
+	list := filepath.Join(dir, "list.csv")
+	os.WriteFile(list, []byte(strings.Join([]string{
+		"# url,name,checksum",
+		srv.URL + "/a.txt,first.txt",
+		srv.URL + "/b.txt",
+		srv.URL + "/c.txt,third.txt,",
+	}, "\n")), 0o644)
+
+	res, err := DownloadList(list, filepath.Join(dir, "out"), ListOptions{CSV: true})
+	if err != nil {
+		u.Fatal(err)
+	}
+	if len(res) != 3 {
+		u.Fatalf("want 3 results, got %d", len(res))
+	}
+	for _, name := range []string{"first.txt", "b.txt", "third.txt"} {
+		if !Exists(filepath.Join(dir, "out", name)) {
+			u.Error("missing", name)
+		}
+	}
+
+	os.WriteFile(list, []byte(srv.URL+"/a.txt,\"broken\n"), 0o644)
+	if _, err := DownloadList(list, filepath.Join(dir, "out"), ListOptions{CSV: true}); err == nil {
+		u.Error("want a parse error")
+	}
+}
+
+func TestDownloadListFiles(u *testing.T) {
+	__(u)
+
+	srv := testListServer(u)
+	dir := u.TempDir()
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(out, 0o755)
+
+	// left over by an interrupted run
+	os.WriteFile(filepath.Join(out, "x.txt.part"), []byte("body of"), 0o644)
+
+	sum := sha256.Sum256([]byte("body of /x.txt"))
+	list := filepath.Join(dir, "list.txt")
+	os.WriteFile(list, []byte(strings.Join([]string{
+		srv.URL + "/x.txt x.txt sha256:" + hex.EncodeToString(sum[:]),
+		srv.URL + "/y.txt x.txt",
+		srv.URL + "/z.txt x.txt",
+		srv.URL + "/bad.txt bad.txt " + strings.Repeat("0", 64),
+	}, "\n")), 0o644)
+
+	res, err := DownloadList(list, out)
+	if err == nil {
+		u.Error("want the checksum error")
+	}
+	want := map[string]string{"x.txt": "body of /x.txt", "x-1.txt": "body of /y.txt", "x-2.txt": "body of /z.txt"}
+	for name, body := range want {
+		b, _ := os.ReadFile(filepath.Join(out, name))
+		if string(b) != body {
+			u.Errorf("%s: want %q, got %q", name, body, b)
+		}
+	}
+	if res[3].Status != "failed" || Exists(filepath.Join(out, "bad.txt")) || Exists(filepath.Join(out, "bad.txt.part")) {
+		u.Error("a file failing the checksum must not stay", res[3])
+	}
+	if Exists(filepath.Join(out, "x.txt.part")) {
+		u.Error("part file left")
+	}
+
+	res, _ = DownloadList(list, out)
+	if res[0].Status != "skipped" || res[1].Status != "skipped" {
+		u.Error("complete files must be skipped", res[0].Status, res[1].Status)
+	}
+}
+
+func TestDownloadListBadNames(u *testing.T) {
+	__(u)
+
+	dir := u.TempDir()
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(out, 0o755)
+
+	var lines []string
+	want := map[string]string{}
+	for i, name := range []string{"..", "/", ".", "a/.."} {
+		src := filepath.Join(dir, fmt.Sprintf("p%d.txt", i))
+		os.WriteFile(src, []byte(name), 0o644)
+		lines = append(lines, "file://"+src+" "+name)
+		want[filepath.Base(src)] = name
+	}
+	list := filepath.Join(dir, "list.txt")
+	os.WriteFile(list, []byte(strings.Join(lines, "\n")), 0o644)
+
+	res, err := DownloadList(list, out)
+	if err != nil {
+		u.Fatal(err)
+	}
+	for _, r := range res {
+		if r.Status != "downloaded" || filepath.Dir(r.File) != out {
+			u.Errorf("%s: %s %s", r.URL, r.Status, r.File)
+		}
+	}
+	for name, body := range want {
+		if b, _ := os.ReadFile(filepath.Join(out, name)); string(b) != body {
+			u.Errorf("%s: got %q", name, b)
+		}
+	}
+}
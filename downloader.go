@@ -1,6 +1,7 @@
 package file
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -33,6 +34,16 @@ type Downloader struct {
 	header        map[string]string
 	breaks        chan bool
 	totalTime     time.Duration
+	retry         RetryPolicy
+	proxy         string
+	profile       Profile
+	jar           http.CookieJar
+	tls           *tls.Config
+	client        *http.Client // range requests, no timeout
+	head          *http.Client
+	ranged        bool // Range was set, no HEAD
+	offset        int64
+	length        int64 // 0 = to the end
 }
 
 type progress struct {
@@ -43,7 +54,7 @@ type progress struct {
 func DownloadFast(from, to string) (a *Downloader) {
 	a = new(Downloader)
 	a.to = to
-	a.breaks = make(chan bool)
+	a.breaks = make(chan bool, 1)
 	a.concurrency = runtime.NumCPU()
 	a.uri = from
 	a.chunks = make(map[int]*os.File)
@@ -54,6 +65,9 @@ func DownloadFast(from, to string) (a *Downloader) {
 	a.stop = make(chan error)
 	a.header = make(map[string]string)
 	a.progress = func(now, total int, percent float64) {}
+	a.retry = currentRetry()
+	a.profile = currentProfile()
+	a.jar = currentJar()
 	return
 }
 
@@ -71,8 +85,58 @@ func (a *Downloader) Header(k, v string) *Downloader {
 	return a
 }
 
+// Retry overrides the package retry policy for this download.
+func (a *Downloader) Retry(p RetryPolicy) *Downloader {
+	a.retry = p
+	return a
+}
+
+// Proxy sends this download through proxy instead of the package pool.
+func (a *Downloader) Proxy(proxy string) *Downloader {
+	a.proxy = proxy
+	return a
+}
+
+// Profile replaces the session headers; the user agent stays the same for HEAD and every range.
+func (a *Downloader) Profile(p Profile) *Downloader {
+	a.profile = p.clone()
+	return a
+}
+
+func (a *Downloader) UserAgent(ua string) *Downloader {
+	a.profile.UserAgent = ua
+	return a
+}
+
+// Cookies sends and stores cookies of this download in j instead of the package jar.
+func (a *Downloader) Cookies(j *CookieJar) *Downloader {
+	a.jar = nil
+	if j != nil {
+		a.jar = j
+	}
+	return a
+}
+
+// TLS uses cfg instead of TransportOptions.TLS. Connections are shared per config
+// pointer, so pass the same cfg to every download rather than a fresh one each time.
+func (a *Downloader) TLS(cfg *tls.Config) *Downloader {
+	a.tls = cfg
+	return a
+}
+
+// Range fetches length bytes at offset in one request, without the HEAD a download
+// normally starts with; length 0 reads to the end. For callers that know what they
+// want, like the byte ranges of an HLS playlist.
+func (a *Downloader) Range(offset, length int64) *Downloader {
+	a.ranged, a.offset, a.length = true, offset, length
+	return a
+}
+
 func (a *Downloader) Stop() {
-	a.breaks <- true
+	select {
+	case a.breaks <- true:
+	default:
+	}
 }
 
 func (a *Downloader) Start(progress ...func(now, total int, percent float64)) (err error) {
@@ -85,8 +149,11 @@ func (a *Downloader) Start(progress ...func(now, total int, percent float64)) (e
 		return
 	}
 
-	go a.catchSignals()
-	go a.userstop(a.breaks)
+	// both watchers end with Start, a program may run thousands of downloads
+	done := make(chan struct{})
+	defer close(done)
+	go a.catchSignals(done)
+	go a.userstop(a.breaks, done)
 
 	if err = a.run(); err != nil {
 		return
@@ -98,11 +165,26 @@ func (a *Downloader) Start(progress ...func(now, total int, percent float64)) (e
 
 }
 
-func (a *Downloader) userstop(s chan bool) {
-	<-s
-	err := errors.New("stop")
-	for i := 0; i < len(a.chunks); i++ {
-		a.stop <- err
+func (a *Downloader) userstop(s chan bool, done chan struct{}) {
+	select {
+	case <-s:
+		a.stopChunks(errors.New("stop"), done)
+	case <-done:
+	}
+}
+
+// stopChunks hands err to every chunk still reading.
+func (a *Downloader) stopChunks(err error, done chan struct{}) {
+	a.RLock()
+	n := len(a.chunks)
+	a.RUnlock()
+
+	for i := 0; i < n; i++ {
+		select {
+		case a.stop <- err:
+		case <-done:
+			return
+		}
 	}
 }
 
@@ -117,7 +199,31 @@ func (a *Downloader) createOutputFile(to string) (err error) {
 }
 
 // run is basically the start method
-func (a *Downloader) run() error {
+func (a *Downloader) run() (err error) {
+
+	if a.ranged {
+		if body, ok, err := openLocal(a.uri); ok {
+			return a.writeLocalRange(body, err)
+		}
+	} else if ok, err := copyLocal(a.uri, a.to); ok {
+		a.out.Close()
+		if err == nil {
+			size := Int(a.to)
+			a.progress(size, size, 100)
+		}
+		return err
+	}
+
+	rt, err := transportTLS(a.tls, a.proxy)
+	if err != nil {
+		return err
+	}
+	a.client = &http.Client{Transport: rt, Jar: a.jar}
+	a.head = &http.Client{Transport: rt, Jar: a.jar, Timeout: 5 * time.Second}
+
+	if a.ranged {
+		return a.processRange()
+	}
 
 	support, contentLength, err := a.getRangeDetails(a.uri)
 	if err != nil {
@@ -188,6 +294,70 @@ func (a *Downloader) process(contentLength int) error {
 	return a.combineChunks()
 }
 
+// processRange downloads the Range in a single request.
+func (a *Downloader) processRange() error {
+	defer a.out.Close()
+
+	r := "" // the whole resource
+	if a.length > 0 {
+		r = fmt.Sprintf("%d-%d", a.offset, a.offset+a.length-1)
+	} else if a.offset > 0 {
+		r = fmt.Sprintf("%d-", a.offset)
+	}
+
+	f, err := os.CreateTemp("", a.fileName+".*.part")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+
+	a.Lock()
+	a.chunks[0] = f
+	a.progressBar[0] = &progress{curr: 0, total: int(a.length)}
+	a.Unlock()
+
+	stop := make(chan struct{})
+	go a.startProgressBar(stop)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	a.downloadFileForRange(wg, a.uri, r, 0, f)
+	stop <- struct{}{}
+
+	if a.err == nil && a.length > 0 && int64(a.progressBar[0].curr) != a.length {
+		a.err = io.ErrUnexpectedEOF
+	}
+	if a.err != nil {
+		os.Remove(a.out.Name())
+		return a.err
+	}
+	return a.combineChunks()
+}
+
+// writeLocalRange saves the Range of a file: or data: body.
+func (a *Downloader) writeLocalRange(body []byte, err error) error {
+	defer a.out.Close()
+
+	end := int64(len(body))
+	if a.length > 0 {
+		end = a.offset + a.length
+	}
+	if err == nil && (a.offset > end || end > int64(len(body))) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		body = body[a.offset:end]
+		_, err = a.out.Write(body)
+	}
+	if err != nil {
+		os.Remove(a.out.Name())
+		return err
+	}
+	a.progress(len(body), len(body), 100)
+	return nil
+}
+
 func (a *Downloader) startProgressBar(stop chan struct{}) {
 
 	ticker := time.NewTicker(time.Second)
@@ -257,28 +427,22 @@ func (a *Downloader) downloadFileForRange(wg *sync.WaitGroup, u, r string, index
 		return
 	}
 
-	request.Header.Add("Range", "bytes="+r)
+	if r != "" {
+		request.Header.Add("Range", "bytes="+r)
+	}
 
 	for k, v := range a.header {
 		request.Header.Add(k, v)
 	}
+	a.profile.apply(request.Header)
 
-	sc, err := a.getDataAndWriteToFile(request, handle, index)
-	if err != nil {
+	if err := a.getDataAndWriteToFile(request, handle, index); err != nil {
 		a.Lock()
 		a.err = err
 		a.Unlock()
 		return
 	}
 
-	//206 = Partial Content
-	if sc != 200 && sc != 206 {
-		a.Lock()
-		a.err = fmt.Errorf("download error: status code %d", sc)
-		a.Unlock()
-		return
-	}
-
 }
 
 // getRangeDetails returns ifRangeIsSupported,statuscode,error
@@ -292,20 +456,24 @@ func (a *Downloader) getRangeDetails(u string) (bool, int, error) {
 	for k, v := range a.header {
 		request.Header.Add(k, v)
 	}
+	a.profile.apply(request.Header)
 
-	sc, headers, _, err := a.doAPICall(request)
+	response, err := a.retry.do(a.head, request)
 	if err != nil {
 		return false, 0, fmt.Errorf("Error calling url : %v", err)
 	}
 
-	switch sc {
+	switch response.StatusCode {
 	case 200, 206:
+		response.Body.Close()
 	case 204:
+		response.Body.Close()
 		return false, 0, fmt.Errorf("nocontent")
 	default:
-		return false, 0, fmt.Errorf("statuscode:%d", sc)
+		return false, 0, statusError(response)
 	}
 
+	headers := response.Header
 	conLen := headers.Get("Content-Length")
 	cl, err := strconv.Atoi(conLen)
 	if err != nil {
@@ -321,40 +489,32 @@ func (a *Downloader) getRangeDetails(u string) (bool, int, error) {
 
 }
 
-// doAPICall will do the api call and return statuscode,headers,data,error respectively
-func (a *Downloader) doAPICall(request *http.Request) (int, http.Header, []byte, error) {
-
-	client := http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	response, err := client.Do(request)
-	if err != nil {
-		return 0, http.Header{}, []byte{}, fmt.Errorf("Error while doing request : %v", err)
-	}
-	defer response.Body.Close()
+// getDataAndWriteToFile will get the response and write to file
+func (a *Downloader) getDataAndWriteToFile(request *http.Request, f io.Writer, index int) error {
 
-	data, err := io.ReadAll(response.Body)
+	response, err := a.retry.do(a.client, request)
 	if err != nil {
-		return 0, http.Header{}, []byte{}, fmt.Errorf("Error while reading response body : %v", err)
+		return fmt.Errorf("Error while doing request : %v", err)
 	}
 
-	return response.StatusCode, response.Header, data, nil
-
-}
-
-// getDataAndWriteToFile will get the response and write to file
-func (a *Downloader) getDataAndWriteToFile(request *http.Request, f io.Writer, index int) (int, error) {
-
-	client := http.Client{
-		Timeout: 0,
+	//206 = Partial Content, anything else is an error page, not our bytes
+	if response.StatusCode != 200 && response.StatusCode != 206 {
+		return statusError(response)
 	}
+	defer response.Body.Close()
 
-	response, err := client.Do(request)
-	if err != nil {
-		return 0, fmt.Errorf("Error while doing request : %v", err)
+	body := io.Reader(response.Body)
+	if r := request.Header.Get("Range"); r != "" && response.StatusCode == 200 {
+		// the server ignored Range and sends everything, cut our part out of it
+		var start, end int64 = 0, -1
+		fmt.Sscanf(r, "bytes=%d-%d", &start, &end)
+		if _, err := io.CopyN(io.Discard, body, start); err != nil {
+			return err
+		}
+		if end >= start {
+			body = io.LimitReader(body, end-start+1)
+		}
 	}
-	defer response.Body.Close()
 
 	//we make buffer of 500 bytes and try to read 500 bytes every iteration.
 	buf := make([]byte, 32*1024)
@@ -363,23 +523,23 @@ func (a *Downloader) getDataAndWriteToFile(request *http.Request, f io.Writer, i
 	for {
 		select {
 		case cErr := <-a.stop:
-			return response.StatusCode, cErr
+			return cErr
 		default:
-			err := a.readBody(response, f, buf, &readTotal, index)
+			err := a.readBody(body, f, buf, &readTotal, index)
 			if err == io.EOF {
-				return response.StatusCode, nil
+				return nil
 			}
 
 			if err != nil {
-				return response.StatusCode, err
+				return err
 			}
 		}
 	}
 }
 
-func (a *Downloader) readBody(response *http.Response, f io.Writer, buf []byte, readTotal *int, index int) error {
+func (a *Downloader) readBody(body io.Reader, f io.Writer, buf []byte, readTotal *int, index int) error {
 
-	r, err := response.Body.Read(buf)
+	r, err := body.Read(buf)
 
 	if r > 0 {
 		if _, writeErr := f.Write(buf[:r]); writeErr != nil {
@@ -387,30 +547,28 @@ func (a *Downloader) readBody(response *http.Response, f io.Writer, buf []byte,
 		}
 	}
 
-	if err != nil {
-		return err
-	}
-
+	// the last bytes may come together with io.EOF
 	*readTotal += r
 
 	a.Lock()
 	a.progressBar[index].curr = *readTotal
 	a.Unlock()
 
-	return nil
+	return err
 }
 
-func (a *Downloader) catchSignals() {
+func (a *Downloader) catchSignals(done chan struct{}) {
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc,
 		syscall.SIGHUP,
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
-	go func() {
-		s := <-sigc
-		for i := 0; i < len(a.chunks); i++ {
-			a.stop <- fmt.Errorf("got stop signal : %v", s)
-		}
-	}()
+	defer signal.Stop(sigc)
+
+	select {
+	case s := <-sigc:
+		a.stopChunks(fmt.Errorf("got stop signal : %v", s), done)
+	case <-done:
+	}
 }
@@ -0,0 +1,101 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloaderStatusError(u *testing.T) {
+	__(u)
+
+	cases := map[string]http.HandlerFunc{
+		"head": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no access", http.StatusForbidden)
+		},
+		"range": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Content-Length", "1000")
+				return
+			}
+			http.Error(w, "no access", http.StatusForbidden)
+		},
+	}
+	for name, h := range cases {
+		srv := httptest.NewServer(h)
+		err := DownloadFast(srv.URL+"/file.bin", filepath.Join(u.TempDir(), "file.bin")).Start()
+		srv.Close()
+
+		var se *HTTPStatusError
+		if !errors.As(err, &se) || se.StatusCode != http.StatusForbidden || se.URL != srv.URL+"/file.bin" {
+			u.Errorf("%s: want *HTTPStatusError 403, got %v", name, err)
+		}
+	}
+}
+
+func TestDownloaderRange(u *testing.T) {
+	__(u)
+
+	content := "0123456789abcdefghij"
+	heads := 0
+	handlers := map[string]http.HandlerFunc{
+		"ranges": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				heads++
+			}
+			http.ServeContent(w, r, "x.bin", time.Time{}, strings.NewReader(content))
+		},
+		"no ranges": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				heads++
+			}
+			io.WriteString(w, content)
+		},
+	}
+	cases := []struct {
+		offset, length int64
+		want           string
+	}{
+		{0, 0, content},
+		{5, 0, content[5:]},
+		{5, 4, content[5:9]},
+		{0, 20, content},
+	}
+
+	before := runtime.NumGoroutine()
+	for name, h := range handlers {
+		srv := httptest.NewServer(h)
+		for _, c := range cases {
+			to := filepath.Join(u.TempDir(), "x.bin")
+			if err := DownloadFast(srv.URL+"/x.bin", to).Range(c.offset, c.length).Start(); err != nil {
+				u.Fatalf("%s %d+%d: %v", name, c.offset, c.length, err)
+			}
+			if b, _ := os.ReadFile(to); string(b) != c.want {
+				u.Errorf("%s %d+%d: got %q", name, c.offset, c.length, b)
+			}
+		}
+
+		to := filepath.Join(u.TempDir(), "x.bin")
+		if err := DownloadFast(srv.URL+"/x.bin", to).Range(15, 10).Start(); err == nil {
+			u.Errorf("%s: a range past the end must fail", name)
+		}
+		srv.Close()
+	}
+	if heads != 0 {
+		u.Errorf("%d HEAD requests", heads)
+	}
+
+	// the stop and signal watchers end with Start
+	time.Sleep(50 * time.Millisecond)
+	if n := runtime.NumGoroutine(); n > before+4 {
+		u.Errorf("%d goroutines before, %d after", before, n)
+	}
+}
@@ -0,0 +1,139 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/shamaton/msgpack/v3"
+)
+
+// HTTPDecodeError is returned when a 200 response cannot be decoded.
+type HTTPDecodeError struct {
+	StatusCode  int
+	URL         string
+	ContentType string
+	Body        []byte // first bytes of the response body
+	Err         error
+}
+
+func (e *HTTPDecodeError) Error() string {
+	return fmt.Sprintf("status code is %d, %s: %v: %s", e.StatusCode, e.ContentType, e.Err, e.Body)
+}
+
+func (e *HTTPDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// fetch sends a GET with the session profile and returns a 200 response.
+// header goes on top of the profile.
+func fetch(c *http.Client, link string, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	currentProfile().apply(req.Header)
+
+	resp, err := currentRetry().do(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+// GetJSON decodes a JSON response into v.
+func GetJSON(link string, v any, proxy ...string) error {
+	return getDecode(link, "application/json", []string{"application/json", "text/json", "+json"}, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(v)
+	}, proxy...)
+}
+
+// GetMsgpack decodes a msgpack response into v.
+func GetMsgpack(link string, v any, proxy ...string) error {
+	return getDecode(link, "application/msgpack, application/x-msgpack", []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}, func(r io.Reader) error {
+		return msgpack.UnmarshalRead(fullReader{r}, v)
+	}, proxy...)
+}
+
+// getDecode streams the body into decode; a missing Content-Type is trusted.
+func getDecode(link, accept string, types []string, decode func(io.Reader) error, proxy ...string) error {
+	c, err := httpclient(proxy...)
+	if err != nil {
+		return err
+	}
+	resp, err := fetch(c, link, map[string]string{"Accept": accept})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fail := func(err error, head []byte) error {
+		return &HTTPDecodeError{
+			StatusCode:  resp.StatusCode,
+			URL:         resp.Request.URL.String(),
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        head,
+			Err:         err,
+		}
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !matchType(ct, types) {
+		head, _ := io.ReadAll(io.LimitReader(resp.Body, statusSnippetSize))
+		return fail(fmt.Errorf("unexpected content type %q", ct), head)
+	}
+
+	head := &headBuffer{max: statusSnippetSize}
+	if err := decode(io.TeeReader(resp.Body, head)); err != nil {
+		return fail(err, head.Bytes())
+	}
+	return nil
+}
+
+func matchType(ct string, types []string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, x := range types {
+		if mt == x || (strings.HasPrefix(x, "+") && strings.HasSuffix(mt, x)) {
+			return true
+		}
+	}
+	return false
+}
+
+// headBuffer keeps only the first max bytes written to it.
+type headBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (a *headBuffer) Write(p []byte) (int, error) {
+	if n := a.max - a.Len(); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		a.Buffer.Write(p[:n])
+	}
+	return len(p), nil
+}
+
+// fullReader fills p on every Read; the msgpack stream decoder does not retry short reads.
+type fullReader struct {
+	r io.Reader
+}
+
+func (a fullReader) Read(p []byte) (int, error) {
+	return io.ReadFull(a.r, p)
+}
@@ -1,22 +1,25 @@
 module github.com/monopolly/file
 
-go 1.23
+go 1.23.0
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/bkaradzic/go-lz4 v1.0.0
 	github.com/cavaliergopher/grab/v3 v3.0.1
 	github.com/fsnotify/fsnotify v1.5.4
+	github.com/klauspost/compress v1.18.0
 	github.com/klauspost/pgzip v1.2.5
 	github.com/monopolly/useragent v0.0.0-20220710193710-261fb66b5f7a
+	github.com/pierrec/lz4/v4 v4.1.33
 	github.com/pquerna/ffjson v0.0.0-20190930134022-aa0246cd15f7
 	github.com/shamaton/msgpack v1.2.1
 	github.com/shamaton/msgpack/v3 v3.1.0
+	github.com/ulikunitz/xz v0.5.17
 	github.com/valyala/fasthttp v1.38.0
+	golang.org/x/net v0.38.0
 )
 
 require (
-	github.com/andybalholm/brotli v1.0.4 // indirect
-	github.com/klauspost/compress v1.15.0 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	golang.org/x/sys v0.3.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
 )
@@ -0,0 +1,395 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// HLSOptions configures DownloadHLS.
+type HLSOptions struct {
+	MaxBandwidth int // best variant not above it, 0 = best variant
+	Parallel     int // segments at once, default 4
+	Progress     func(done, total int, bytes int64)
+}
+
+type hlsVariant struct {
+	bandwidth int
+	uri       string
+}
+
+type hlsKey struct {
+	method string
+	uri    string
+	iv     []byte // nil = media sequence number
+}
+
+type hlsSegment struct {
+	uri    string
+	seq    int64
+	key    *hlsKey
+	offset int64 // byte range, length 0 = whole resource
+	length int64
+}
+
+var errPlaylist = errors.New("hls: not an m3u8 playlist")
+
+// DownloadHLS saves an HTTP Live Streaming recording as one .ts file.
+// Master playlists are resolved to a variant by bandwidth; AES-128 segments are decrypted.
+func DownloadHLS(playlistURL, out string, opts ...HLSOptions) (err error) {
+	var o HLSOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Parallel <= 0 {
+		o.Parallel = 4
+	}
+	if o.Progress == nil {
+		o.Progress = func(done, total int, bytes int64) {}
+	}
+
+	base, err := url.Parse(playlistURL)
+	if err != nil {
+		return
+	}
+	body, err := Get(playlistURL)
+	if err != nil {
+		return
+	}
+
+	variants, segments, err := parseM3U8(base, body)
+	if err != nil {
+		return
+	}
+	if len(variants) > 0 {
+		v := pickVariant(variants, o.MaxBandwidth)
+		if base, err = url.Parse(v.uri); err != nil {
+			return
+		}
+		if body, err = Get(v.uri); err != nil {
+			return
+		}
+		if _, segments, err = parseM3U8(base, body); err != nil {
+			return
+		}
+	}
+	if len(segments) == 0 {
+		return errors.New("hls: playlist has no segments")
+	}
+
+	// parts are closed once written, a long recording has thousands of them
+	parts := make([]string, len(segments))
+	defer func() {
+		for _, name := range parts {
+			if name != "" {
+				os.Remove(name)
+			}
+		}
+	}()
+
+	var (
+		done    int32
+		written int64
+		keys    sync.Map // uri => []byte
+		errMu   sync.Mutex
+		first   error
+	)
+	sem := make(chan struct{}, o.Parallel)
+	wg := &sync.WaitGroup{}
+
+	failed := func() bool {
+		errMu.Lock()
+		defer errMu.Unlock()
+		return first != nil
+	}
+
+	o.Progress(0, len(segments), 0)
+	for i, s := range segments {
+		sem <- struct{}{}
+		// the recording is lost anyway, don't start the rest
+		if failed() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int, s hlsSegment) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			part, n, err := hlsSegmentFile(s, &keys, Filename(out))
+			parts[i] = part
+			if err != nil {
+				errMu.Lock()
+				if first == nil {
+					first = fmt.Errorf("hls: segment %d: %w", i, err)
+				}
+				errMu.Unlock()
+				return
+			}
+			o.Progress(int(atomic.AddInt32(&done, 1)), len(segments), atomic.AddInt64(&written, n))
+		}(i, s)
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
+
+	dst, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+
+	for _, part := range parts {
+		if err = appendFile(dst, part); err != nil {
+			return
+		}
+	}
+	return dst.Close()
+}
+
+func appendFile(dst io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(dst, f)
+	return err
+}
+
+// hlsSegmentFile downloads one segment with a Downloader into a closed temporary file
+// and decrypts it there; keys are small and read with Get.
+func hlsSegmentFile(s hlsSegment, keys *sync.Map, name string) (string, int64, error) {
+	f, err := os.CreateTemp("", name+".*.part")
+	if err != nil {
+		return "", 0, err
+	}
+	part := f.Name()
+	f.Close()
+
+	// the playlist says what to fetch, no HEAD per segment
+	if err := DownloadFast(s.uri, part).Range(s.offset, s.length).Start(); err != nil {
+		return part, 0, err
+	}
+	if s.key == nil || s.key.method != "AES-128" {
+		return part, Size(part), nil
+	}
+
+	key, ok := keys.Load(s.key.uri)
+	if !ok {
+		k, err := Get(s.key.uri)
+		if err != nil {
+			return part, 0, fmt.Errorf("key: %w", err)
+		}
+		key, _ = keys.LoadOrStore(s.key.uri, k)
+	}
+	body, err := os.ReadFile(part)
+	if err != nil {
+		return part, 0, err
+	}
+	if body, err = hlsDecrypt(body, key.([]byte), s.key.iv, s.seq); err != nil {
+		return part, 0, err
+	}
+	return part, int64(len(body)), Rewrite(part, body)
+}
+
+func hlsDecrypt(body, key, iv []byte, seq int64) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if iv == nil {
+		iv = make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint64(iv[8:], uint64(seq))
+	}
+	if len(body)%aes.BlockSize != 0 {
+		return nil, errors.New("hls: encrypted segment is not a multiple of the block size")
+	}
+
+	out := make([]byte, len(body))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, body)
+
+	// PKCS7
+	if n := len(out); n > 0 {
+		pad := int(out[n-1])
+		if pad == 0 || pad > aes.BlockSize || pad > n || !bytes.Equal(out[n-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+			return nil, errors.New("hls: bad padding, wrong key?")
+		}
+		out = out[:n-pad]
+	}
+	return out, nil
+}
+
+func pickVariant(list []hlsVariant, maxBandwidth int) hlsVariant {
+	best, lowest := -1, 0
+	for i, v := range list {
+		if v.bandwidth < list[lowest].bandwidth {
+			lowest = i
+		}
+		if maxBandwidth > 0 && v.bandwidth > maxBandwidth {
+			continue
+		}
+		if best < 0 || v.bandwidth > list[best].bandwidth {
+			best = i
+		}
+	}
+	if best < 0 {
+		return list[lowest]
+	}
+	return list[best]
+}
+
+// parseM3U8 returns the variants of a master playlist or the segments of a media one.
+func parseM3U8(base *url.URL, body []byte) (variants []hlsVariant, segments []hlsSegment, err error) {
+	scanner := lineScanner(bytes.NewReader(body))
+	if !scanner.Scan() || !strings.HasPrefix(strings.TrimPrefix(scanner.Text(), "\uFEFF"), "#EXTM3U") {
+		return nil, nil, errPlaylist
+	}
+
+	resolve := func(ref string) (string, error) {
+		u, err := base.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	var (
+		seq         int64
+		key         *hlsKey
+		streamInf   map[string]string
+		rangeLength int64
+		rangeOffset int64
+		hasRange    bool
+		nextOffset  int64
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			streamInf = hlsAttrs(line[len("#EXT-X-STREAM-INF:"):])
+
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			seq, _ = strconv.ParseInt(line[len("#EXT-X-MEDIA-SEQUENCE:"):], 10, 64)
+
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			attrs := hlsAttrs(line[len("#EXT-X-KEY:"):])
+			switch attrs["METHOD"] {
+			case "NONE":
+				key = nil
+			case "AES-128":
+				key = &hlsKey{method: "AES-128"}
+				if key.uri, err = resolve(attrs["URI"]); err != nil {
+					return
+				}
+				if iv := attrs["IV"]; iv != "" {
+					iv = strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")
+					if key.iv, err = hex.DecodeString(fmt.Sprintf("%032s", iv)); err != nil {
+						return nil, nil, fmt.Errorf("hls: bad IV: %w", err)
+					}
+				}
+			default:
+				return nil, nil, fmt.Errorf("hls: unsupported encryption %q", attrs["METHOD"])
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			attrs := hlsAttrs(line[len("#EXT-X-MAP:"):])
+			s := hlsSegment{seq: seq}
+			if s.uri, err = resolve(attrs["URI"]); err != nil {
+				return
+			}
+			if r := attrs["BYTERANGE"]; r != "" {
+				s.length, s.offset, _ = hlsByteRange(r, 0)
+			}
+			segments = append(segments, s)
+
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			var ok bool
+			rangeLength, rangeOffset, ok = hlsByteRange(line[len("#EXT-X-BYTERANGE:"):], nextOffset)
+			hasRange = ok
+
+		case strings.HasPrefix(line, "#"):
+
+		case streamInf != nil:
+			v := hlsVariant{}
+			v.bandwidth, _ = strconv.Atoi(streamInf["BANDWIDTH"])
+			if v.uri, err = resolve(line); err != nil {
+				return
+			}
+			variants = append(variants, v)
+			streamInf = nil
+
+		default:
+			s := hlsSegment{seq: seq, key: key}
+			if s.uri, err = resolve(line); err != nil {
+				return
+			}
+			if hasRange {
+				s.offset, s.length = rangeOffset, rangeLength
+				nextOffset = rangeOffset + rangeLength
+				hasRange = false
+			}
+			segments = append(segments, s)
+			seq++
+		}
+	}
+	return variants, segments, scanner.Err()
+}
+
+// hlsByteRange parses "length[@offset]"; without offset the range follows the previous one.
+func hlsByteRange(v string, next int64) (length, offset int64, ok bool) {
+	l, o, hasOffset := strings.Cut(v, "@")
+	length, err := strconv.ParseInt(l, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	offset = next
+	if hasOffset {
+		if offset, err = strconv.ParseInt(o, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return length, offset, true
+}
+
+// hlsAttrs parses KEY=VALUE,KEY="quoted, value" attribute lists.
+func hlsAttrs(s string) map[string]string {
+	res := make(map[string]string)
+	r := bufio.NewReader(strings.NewReader(s))
+	for {
+		k, err := r.ReadString('=')
+		if err != nil {
+			return res
+		}
+		k = strings.TrimSpace(strings.TrimSuffix(k, "="))
+
+		var v string
+		if b, _ := r.Peek(1); len(b) == 1 && b[0] == '"' {
+			_, _ = r.ReadByte()
+			v, _ = r.ReadString('"')
+			v = strings.TrimSuffix(v, `"`)
+			_, _ = r.ReadString(',')
+		} else {
+			v, _ = r.ReadString(',')
+			v = strings.TrimSuffix(v, ",")
+		}
+		res[k] = strings.TrimSpace(v)
+	}
+}
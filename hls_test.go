@@ -0,0 +1,151 @@
+package file
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testEncrypt(b, key, iv []byte) []byte {
+	pad := aes.BlockSize - len(b)%aes.BlockSize
+	b = append(append([]byte(nil), b...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(b))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, b)
+	return out
+}
+
+func TestDownloadHLS(u *testing.T) {
+	__(u)
+
+	key := []byte("0123456789abcdef")
+	segs := []string{"seg-zero-", "seg-one-", "seg-two"}
+	failed := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/master.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=100000,CODECS=\"a,b\"\nlow/index.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=900000\nhigh/index.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=500000\nmid/index.m3u8\n")
+	})
+	mux.HandleFunc("/mid/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:7\n#EXT-X-KEY:METHOD=AES-128,URI=\"/key\"\n#EXTINF:1,\ns0.ts\n#EXTINF:1,\ns1.ts\n#EXT-X-KEY:METHOD=NONE\n#EXTINF:1,\ns2.ts\n#EXT-X-ENDLIST\n")
+	})
+	mux.HandleFunc("/key", func(w http.ResponseWriter, r *http.Request) { w.Write(key) })
+	for i, seg := range segs {
+		i, seg := i, seg
+		mux.HandleFunc(fmt.Sprintf("/mid/s%d.ts", i), func(w http.ResponseWriter, r *http.Request) {
+			if i == 1 && !failed {
+				failed = true
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if i == 2 {
+				w.Write([]byte(seg))
+				return
+			}
+			iv := make([]byte, aes.BlockSize)
+			iv[15] = byte(7 + i) // media sequence number
+			w.Write(testEncrypt([]byte(seg), key, iv))
+		})
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	out := filepath.Join(u.TempDir(), "out.ts")
+	var done, total int
+	var size int64
+	err := DownloadHLS(srv.URL+"/master.m3u8", out, HLSOptions{
+		MaxBandwidth: 600000,
+		Parallel:     1,
+		Progress:     func(d, t int, b int64) { done, total, size = d, t, b },
+	})
+	if err != nil {
+		u.Fatal(err)
+	}
+	b, _ := os.ReadFile(out)
+	if string(b) != strings.Join(segs, "") {
+		u.Fatalf("got %q", b)
+	}
+	if done != 3 || total != 3 || size != int64(len(b)) {
+		u.Fatal("progress", done, total, size)
+	}
+}
+
+func TestDownloadHLSManySegments(u *testing.T) {
+	__(u)
+
+	// more parts than the usual 1024 open files
+	dir := u.TempDir()
+	var playlist, want strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	for i := 0; i < 1500; i++ {
+		body := fmt.Sprintf("[%d]", i)
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.ts", i)), []byte(body), 0o644)
+		fmt.Fprintf(&playlist, "#EXTINF:6,\n%d.ts\n", i)
+		want.WriteString(body)
+	}
+	os.WriteFile(filepath.Join(dir, "all.m3u8"), []byte(playlist.String()), 0o644)
+
+	out := filepath.Join(dir, "out.ts")
+	if err := DownloadHLS("file://"+filepath.Join(dir, "all.m3u8"), out); err != nil {
+		u.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); string(b) != want.String() {
+		u.Fatal("joined segments differ")
+	}
+}
+
+func TestDownloadHLSByteRange(u *testing.T) {
+	__(u)
+
+	dir := u.TempDir()
+	os.WriteFile(filepath.Join(dir, "all.ts"), []byte("AAAABBBBCC"), 0o644)
+	os.WriteFile(filepath.Join(dir, "p.m3u8"), []byte("#EXTM3U\n#EXT-X-BYTERANGE:4@0\nall.ts\n#EXT-X-BYTERANGE:4\nall.ts\n#EXT-X-BYTERANGE:2\nall.ts\n"), 0o644)
+
+	out := filepath.Join(dir, "out.ts")
+	if err := DownloadHLS("file://"+filepath.Join(dir, "p.m3u8"), out); err != nil {
+		u.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); string(b) != "AAAABBBBCC" {
+		u.Fatalf("got %q", b)
+	}
+}
+
+func TestDownloadHLSStopsOnError(u *testing.T) {
+	__(u)
+
+	var mu sync.Mutex
+	requested := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n")
+		for i := 0; i < 50; i++ {
+			fmt.Fprintf(w, "#EXTINF:1,\ns%d.ts\n", i)
+		}
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested++
+		mu.Unlock()
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	err := DownloadHLS(srv.URL+"/index.m3u8", filepath.Join(u.TempDir(), "out.ts"), HLSOptions{Parallel: 2})
+	var se *HTTPStatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+		u.Fatalf("want a 404, got %v", err)
+	}
+	if requested > 4 {
+		u.Fatalf("%d segments requested after the first failed", requested)
+	}
+}
@@ -0,0 +1,122 @@
+package file
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var errDataURL = errors.New("malformed data url")
+
+// localPath turns file:///abs/path, file://localhost/abs/path and file:rel/path into a path.
+func localPath(link string) (string, bool) {
+	if !hasScheme(link, "file") {
+		return "", false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+	if u.Opaque != "" {
+		p, err := url.PathUnescape(u.Opaque)
+		if err != nil {
+			return "", false
+		}
+		return filepath.FromSlash(p), true
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", false
+	}
+
+	p := u.Path
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), true
+}
+
+func isDataURL(link string) bool {
+	return hasScheme(link, "data")
+}
+
+func hasScheme(link, scheme string) bool {
+	return len(link) > len(scheme) && link[len(scheme)] == ':' && strings.EqualFold(link[:len(scheme)], scheme)
+}
+
+// ParseDataURL decodes an RFC 2397 data: URL, base64 or percent-encoded.
+func ParseDataURL(link string) (body []byte, mediatype string, err error) {
+	if !isDataURL(link) {
+		return nil, "", errDataURL
+	}
+
+	meta, data, ok := strings.Cut(link[len("data:"):], ",")
+	if !ok {
+		return nil, "", errDataURL
+	}
+
+	encoded := false
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		encoded = true
+		meta = meta[:len(meta)-len(";base64")]
+	}
+	mediatype = meta
+	if mediatype == "" || strings.HasPrefix(mediatype, ";") {
+		mediatype = "text/plain" + mediatype
+		if !strings.Contains(mediatype, "charset=") {
+			mediatype += ";charset=US-ASCII"
+		}
+	}
+
+	raw, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if !encoded {
+		return []byte(raw), mediatype, nil
+	}
+
+	raw = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
+		}
+		return r
+	}, raw)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if body, err = enc.DecodeString(raw); err == nil {
+			return body, mediatype, nil
+		}
+	}
+	return nil, "", err
+}
+
+// openLocal reads file: and data: links; ok is false for anything else.
+func openLocal(link string) (body []byte, ok bool, err error) {
+	if p, ok := localPath(link); ok {
+		body, err = Open(p)
+		return body, true, err
+	}
+	if isDataURL(link) {
+		body, _, err = ParseDataURL(link)
+		return body, true, err
+	}
+	return nil, false, nil
+}
+
+// copyLocal saves file: and data: links to to; ok is false for anything else.
+func copyLocal(link, to string) (ok bool, err error) {
+	if p, ok := localPath(link); ok {
+		return true, Copy(p, to)
+	}
+	if isDataURL(link) {
+		body, _, err := ParseDataURL(link)
+		if err != nil {
+			return true, err
+		}
+		return true, Rewrite(to, body)
+	}
+	return false, nil
+}
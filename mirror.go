@@ -0,0 +1,380 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// attributes that reference other resources, per tag
+var mirrorAttrs = map[string][]string{
+	"a":      {"href"},
+	"img":    {"src", "srcset"},
+	"script": {"src"},
+	"link":   {"href"},
+	"source": {"src", "srcset"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"track":  {"src"},
+	"embed":  {"src"},
+	"input":  {"src"},
+}
+
+var cssURL = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)|@import\s+(['"])([^'"]+)(['"])`)
+
+type mirror struct {
+	dir    string
+	host   string
+	depth  int
+	saved  map[string]string // url => local file
+	queue  []mirrorPage
+	errs   []error
+	client *http.Client
+}
+
+type mirrorPage struct {
+	link  string
+	level int
+}
+
+// MirrorPage saves the page at link and the images, styles and scripts it uses into dir,
+// rewriting references to relative local paths. Links to pages of the same host are
+// followed depth levels deep; assets are saved from any host, each once.
+// Same-host links to anything but text/html, say report.pdf, are saved as they are.
+func MirrorPage(link, dir string, depth int) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("mirror: unsupported url %q", link)
+	}
+
+	c, err := httpclient()
+	if err != nil {
+		return err
+	}
+
+	a := &mirror{
+		dir:    dir,
+		host:   u.Host,
+		depth:  depth,
+		saved:  make(map[string]string),
+		client: c,
+	}
+	a.enqueue(u, 0)
+
+	for len(a.queue) > 0 {
+		p := a.queue[0]
+		a.queue = a.queue[1:]
+		if err := a.page(p); err != nil {
+			a.errs = append(a.errs, fmt.Errorf("%s: %w", p.link, err))
+		}
+	}
+	return errors.Join(a.errs...)
+}
+
+// local maps a url to a file under dir: host/path, index.html for directories,
+// .html for extensionless pages and a query hash to keep variants apart.
+func (a *mirror) local(u *url.URL, page bool) string {
+	p := u.EscapedPath()
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	p, _ = url.PathUnescape(p)
+	p = path.Clean("/" + p)
+
+	ext := path.Ext(p)
+	if u.RawQuery != "" {
+		sum := sha1.Sum([]byte(u.RawQuery))
+		p = strings.TrimSuffix(p, ext) + "-" + hex.EncodeToString(sum[:4]) + ext
+	}
+	if page && ext != ".html" && ext != ".htm" {
+		p += ".html"
+	}
+
+	host := strings.NewReplacer(":", "_").Replace(u.Host)
+	return filepath.Join(a.dir, host, filepath.FromSlash(p))
+}
+
+func (a *mirror) enqueue(u *url.URL, level int) string {
+	u.Fragment = ""
+	key := u.String()
+	if f, ok := a.saved[key]; ok {
+		return f
+	}
+	f := a.local(u, true)
+	a.saved[key] = f
+	a.queue = append(a.queue, mirrorPage{link: key, level: level})
+	return f
+}
+
+// asset saves u once and returns its local file.
+func (a *mirror) asset(u *url.URL, css bool) string {
+	u.Fragment = ""
+	key := u.String()
+	if f, ok := a.saved[key]; ok {
+		return f
+	}
+	f := a.local(u, false)
+	a.saved[key] = f
+
+	var err error
+	if css {
+		err = a.css(u, f)
+	} else {
+		err = DownloadFile(key, f, nil)
+	}
+	if err != nil {
+		a.errs = append(a.errs, fmt.Errorf("%s: %w", key, err))
+	}
+	return f
+}
+
+func (a *mirror) page(p mirrorPage) error {
+	base, err := url.Parse(p.link)
+	if err != nil {
+		return err
+	}
+	file := a.saved[base.String()]
+
+	resp, err := fetch(a.client, p.link, nil)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	// the guess in isPage was wrong, keep the bytes as they are
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !isHTML(ct) {
+		return Save(file, body)
+	}
+
+	var out bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return z.Err()
+			}
+			break
+		}
+		raw := append([]byte(nil), z.Raw()...)
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+
+		t := z.Token()
+		if t.Data == "base" {
+			for _, attr := range t.Attr {
+				if attr.Key == "href" {
+					if u, err := base.Parse(attr.Val); err == nil {
+						base = u
+					}
+				}
+			}
+			continue // references become relative to the saved file
+		}
+
+		names, ok := mirrorAttrs[t.Data]
+		if !ok {
+			out.Write(raw)
+			continue
+		}
+
+		changed := false
+		for i, attr := range t.Attr {
+			if !contains(names, attr.Key) {
+				continue
+			}
+			var v string
+			if attr.Key == "srcset" {
+				v = a.srcset(base, file, attr.Val)
+			} else {
+				v = a.ref(base, file, t, attr.Val, p.level)
+			}
+			if v != attr.Val {
+				t.Attr[i].Val = v
+				changed = true
+			}
+		}
+		if changed {
+			out.WriteString(t.String())
+		} else {
+			out.Write(raw)
+		}
+	}
+
+	return Save(file, out.Bytes())
+}
+
+// ref resolves one attribute value and returns what the saved page should hold instead.
+func (a *mirror) ref(base *url.URL, from string, t html.Token, val string, level int) string {
+	v := strings.TrimSpace(val)
+	if v == "" || strings.HasPrefix(v, "#") {
+		return val
+	}
+	u, err := base.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return val
+	}
+	frag := u.Fragment
+
+	var f string
+	switch {
+	case t.Data == "a":
+		u.Fragment = ""
+		if saved, ok := a.saved[u.String()]; ok {
+			f = saved
+		} else if u.Host != a.host || level >= a.depth {
+			u.Fragment = frag
+			return u.String()
+		} else if !a.isPage(u) {
+			f = a.asset(u, false)
+		} else {
+			f = a.enqueue(u, level+1)
+		}
+	case t.Data == "link":
+		rel := strings.ToLower(attrValue(t, "rel"))
+		if strings.Contains(rel, "stylesheet") {
+			f = a.asset(u, true)
+		} else if strings.Contains(rel, "icon") || strings.Contains(rel, "preload") || strings.Contains(rel, "manifest") {
+			f = a.asset(u, false)
+		} else {
+			return u.String()
+		}
+	default:
+		f = a.asset(u, false)
+	}
+
+	r := relLink(from, f)
+	if frag != "" {
+		r += "#" + frag
+	}
+	return r
+}
+
+// srcset rewrites "url 1x, url 2x" lists.
+func (a *mirror) srcset(base *url.URL, from, val string) string {
+	list := strings.Split(val, ",")
+	for i, x := range list {
+		p := strings.Fields(x)
+		if len(p) == 0 {
+			continue
+		}
+		u, err := base.Parse(p[0])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		p[0] = relLink(from, a.asset(u, false))
+		list[i] = strings.Join(p, " ")
+	}
+	return strings.Join(list, ", ")
+}
+
+// css saves a stylesheet with its url() and @import references made local.
+func (a *mirror) css(u *url.URL, file string) error {
+	body, err := Get(u.String())
+	if err != nil {
+		return err
+	}
+
+	body = cssURL.ReplaceAllFunc(body, func(m []byte) []byte {
+		p := cssURL.FindSubmatch(m)
+		ref, quote, isImport := p[2], p[1], false
+		if len(ref) == 0 {
+			ref, quote, isImport = p[5], p[4], true
+		}
+		r, err := u.Parse(strings.TrimSpace(string(ref)))
+		if err != nil || (r.Scheme != "http" && r.Scheme != "https") {
+			return m
+		}
+		local := relLink(file, a.asset(r, isImport || strings.HasSuffix(r.Path, ".css")))
+		if isImport {
+			return []byte("@import " + string(quote) + local + string(quote))
+		}
+		return []byte("url(" + string(quote) + local + string(quote) + ")")
+	})
+
+	return Save(file, body)
+}
+
+// isPage tells same-host links to HTML pages from links to files like report.pdf:
+// by extension when it is known, by a HEAD request otherwise.
+func (a *mirror) isPage(u *url.URL) bool {
+	p := u.Path
+	if p == "" || strings.HasSuffix(p, "/") {
+		return true
+	}
+	switch ext := strings.ToLower(path.Ext(p)); ext {
+	case ".html", ".htm", ".xhtml":
+		return true
+	case "":
+	default:
+		if ct := mime.TypeByExtension(ext); ct != "" {
+			return isHTML(ct)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return true
+	}
+	currentProfile().apply(req.Header)
+	resp, err := currentRetry().do(a.client, req)
+	if err != nil {
+		return true
+	}
+	resp.Body.Close()
+
+	ct := resp.Header.Get("Content-Type")
+	return resp.StatusCode != http.StatusOK || ct == "" || isHTML(ct)
+}
+
+func isHTML(ct string) bool {
+	mt, _, _ := mime.ParseMediaType(ct)
+	return mt == "text/html" || mt == "application/xhtml+xml"
+}
+
+func relLink(from, to string) string {
+	r, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		return filepath.ToSlash(to)
+	}
+	return (&url.URL{Path: filepath.ToSlash(r)}).String()
+}
+
+func attrValue(t html.Token, key string) string {
+	for _, attr := range t.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func contains(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
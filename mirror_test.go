@@ -0,0 +1,69 @@
+package file
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorPage(u *testing.T) {
+	__(u)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body><a href="data.zip">zip</a> <a href="/report.pdf">pdf</a> <a href="/export">csv</a> <a href="/about">about</a> <img src="/logo.png"></body></html>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<p>about <a href="/">home</a></p>`)
+	})
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		fmt.Fprint(w, "a,b\n<a href=x>")
+	})
+	for name, body := range map[string]string{"/data.zip": "PK-zip", "/report.pdf": "%PDF-1.7", "/logo.png": "png"} {
+		body := body
+		mux.HandleFunc(name, func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, body) })
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := u.TempDir()
+	if err := MirrorPage(srv.URL+"/", dir, 1); err != nil {
+		u.Fatal(err)
+	}
+	root := filepath.Join(dir, strings.NewReplacer(":", "_").Replace(strings.TrimPrefix(srv.URL, "http://")))
+
+	want := map[string]string{
+		"data.zip":   "PK-zip",
+		"report.pdf": "%PDF-1.7",
+		"export":     "a,b\n<a href=x>",
+		"logo.png":   "png",
+	}
+	for name, body := range want {
+		b, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil || string(b) != body {
+			u.Errorf("%s: %v %q", name, err, b)
+		}
+	}
+	for _, name := range []string{"data.zip.html", "report.pdf.html", "export.html"} {
+		if Exists(filepath.Join(root, name)) {
+			u.Errorf("%s saved as a page", name)
+		}
+	}
+
+	index, _ := os.ReadFile(filepath.Join(root, "index.html"))
+	for _, ref := range []string{`href="data.zip"`, `href="report.pdf"`, `href="export"`, `href="about.html"`} {
+		if !strings.Contains(string(index), ref) {
+			u.Errorf("index.html lacks %s: %s", ref, index)
+		}
+	}
+	if !Exists(filepath.Join(root, "about.html")) {
+		u.Error("about.html not saved")
+	}
+}
@@ -0,0 +1,151 @@
+package file
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/monopolly/useragent"
+)
+
+// Profile is a consistent set of request headers of one client.
+type Profile struct {
+	UserAgent string
+	Header    map[string]string
+}
+
+// Generic keeps the historical headers of the package with a random user agent.
+func Generic() Profile {
+	return Profile{
+		UserAgent: useragent.Generate(),
+		Header: map[string]string{
+			"Accept":          "*/*",
+			"Accept-Language": "en-us",
+			"DNT":             "1",
+		},
+	}
+}
+
+func Chrome() Profile {
+	return Profile{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		Header: map[string]string{
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+			"Accept-Language":           "en-US,en;q=0.9",
+			"Sec-Ch-Ua":                 `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+			"Sec-Ch-Ua-Mobile":          "?0",
+			"Sec-Ch-Ua-Platform":        `"Windows"`,
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"Upgrade-Insecure-Requests": "1",
+		},
+	}
+}
+
+func Firefox() Profile {
+	return Profile{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+		Header: map[string]string{
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8",
+			"Accept-Language":           "en-US,en;q=0.5",
+			"DNT":                       "1",
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"Upgrade-Insecure-Requests": "1",
+		},
+	}
+}
+
+// Mobile is Chrome on Android.
+func Mobile() Profile {
+	return Profile{
+		UserAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+		Header: map[string]string{
+			"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+			"Accept-Language":           "en-US,en;q=0.9",
+			"Sec-Ch-Ua":                 `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+			"Sec-Ch-Ua-Mobile":          "?1",
+			"Sec-Ch-Ua-Platform":        `"Android"`,
+			"Sec-Fetch-Dest":            "document",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Fetch-Site":            "none",
+			"Sec-Fetch-User":            "?1",
+			"Upgrade-Insecure-Requests": "1",
+		},
+	}
+}
+
+// Bot identifies honestly: name/version and a page describing the crawler.
+func Bot(name, version, info string) Profile {
+	ua := "Mozilla/5.0 (compatible; " + name + "/" + version
+	if info != "" {
+		ua += "; +" + info
+	}
+	return Profile{
+		UserAgent: ua + ")",
+		Header: map[string]string{
+			"Accept": "*/*",
+		},
+	}
+}
+
+// apply sets the profile headers, keeping the ones already on h.
+func (p Profile) apply(h http.Header) {
+	for k, v := range p.Header {
+		if h.Get(k) == "" {
+			h.Set(k, v)
+		}
+	}
+	if p.UserAgent != "" && h.Get("User-Agent") == "" {
+		h.Set("User-Agent", p.UserAgent)
+	}
+}
+
+var (
+	profileMu sync.RWMutex
+	profile   *Profile
+)
+
+// SetProfile fixes the headers used by every HTTP helper of the package.
+func SetProfile(p Profile) {
+	profileMu.Lock()
+	profile = &p
+	profileMu.Unlock()
+}
+
+// SetUserAgent overrides the user agent of the current profile.
+func SetUserAgent(ua string) {
+	p := currentProfile()
+	p.UserAgent = ua
+	SetProfile(p)
+}
+
+// currentProfile returns the session profile; Generic is picked once on first use.
+func currentProfile() Profile {
+	profileMu.RLock()
+	p := profile
+	profileMu.RUnlock()
+	if p != nil {
+		return p.clone()
+	}
+
+	profileMu.Lock()
+	defer profileMu.Unlock()
+	if profile == nil {
+		g := Generic()
+		profile = &g
+	}
+	return profile.clone()
+}
+
+func (p Profile) clone() Profile {
+	h := make(map[string]string, len(p.Header))
+	for k, v := range p.Header {
+		h[k] = v
+	}
+	p.Header = h
+	return p
+}
@@ -0,0 +1,258 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimit is the politeness budget of a single host.
+type HostLimit struct {
+	Rate        float64 // requests per second, 0 = unlimited
+	Burst       int     // requests allowed back to back before Rate kicks in
+	Concurrency int     // requests in flight, 0 = unlimited
+}
+
+var limits = &hostLimits{
+	domains: make(map[string]HostLimit),
+	hosts:   make(map[string]*hostState),
+}
+
+type hostLimits struct {
+	sync.Mutex
+	fallback HostLimit
+	domains  map[string]HostLimit
+	robots   bool
+	hosts    map[string]*hostState
+}
+
+type hostState struct {
+	sync.Mutex
+	interval time.Duration
+	burst    int
+	tat      time.Time     // theoretical arrival time of the next request
+	sem      chan struct{} // nil when concurrency is unlimited
+	robots   sync.Once
+}
+
+// SetHostLimit sets the limit every host gets unless a domain override matches.
+func SetHostLimit(l HostLimit) {
+	limits.Lock()
+	limits.fallback = l
+	limits.hosts = make(map[string]*hostState)
+	limits.Unlock()
+}
+
+// SetDomainLimit overrides the limit for domain and its subdomains.
+func SetDomainLimit(domain string, l HostLimit) {
+	limits.Lock()
+	limits.domains[strings.ToLower(domain)] = l
+	limits.hosts = make(map[string]*hostState)
+	limits.Unlock()
+}
+
+// RobotsCrawlDelay makes the limiter honor Crawl-delay from robots.txt of every host.
+func RobotsCrawlDelay(on bool) {
+	limits.Lock()
+	limits.robots = on
+	limits.hosts = make(map[string]*hostState)
+	limits.Unlock()
+}
+
+// limitFor picks the most specific domain override.
+func (a *hostLimits) limitFor(hostname string) HostLimit {
+	hostname = strings.ToLower(hostname)
+	l, best := a.fallback, -1
+	for d, x := range a.domains {
+		if (hostname == d || strings.HasSuffix(hostname, "."+d)) && len(d) > best {
+			l, best = x, len(d)
+		}
+	}
+	return l
+}
+
+func (a *hostLimits) state(req *http.Request) (s *hostState, robots bool) {
+	a.Lock()
+	defer a.Unlock()
+
+	s = a.hosts[req.URL.Host]
+	if s == nil {
+		l := a.limitFor(req.URL.Hostname())
+		s = &hostState{burst: l.Burst}
+		if l.Rate > 0 {
+			s.interval = time.Duration(float64(time.Second) / l.Rate)
+		}
+		if s.burst < 1 {
+			s.burst = 1
+		}
+		if l.Concurrency > 0 {
+			s.sem = make(chan struct{}, l.Concurrency)
+		}
+		a.hosts[req.URL.Host] = s
+	}
+	return s, a.robots
+}
+
+// acquire blocks until req may be sent and returns the release func.
+func (a *hostLimits) acquire(req *http.Request, base http.RoundTripper) (func(), error) {
+	s, robots := a.state(req)
+	if robots {
+		s.robots.Do(func() {
+			d := crawlDelay(req, base)
+			s.Lock()
+			if d > s.interval {
+				s.interval = d
+				s.burst = 1
+			}
+			s.Unlock()
+		})
+	}
+
+	ctx := req.Context()
+	if s.sem != nil {
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if s.sem != nil {
+			<-s.sem
+		}
+	}
+
+	if wait := s.reserve(); wait > 0 {
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// reserve books the next slot and returns how long to wait for it.
+func (s *hostState) reserve() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.interval <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	if s.tat.Before(now) {
+		s.tat = now
+	}
+	at := s.tat.Add(-time.Duration(s.burst-1) * s.interval)
+	s.tat = s.tat.Add(s.interval)
+	return at.Sub(now)
+}
+
+// crawlDelay reads Crawl-delay of the "*" group from robots.txt of the request host.
+func crawlDelay(req *http.Request, base http.RoundTripper) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL.Scheme+"://"+req.URL.Host+"/robots.txt", nil)
+	if err != nil {
+		return 0
+	}
+	resp, err := base.RoundTrip(r)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return 0
+	}
+
+	var (
+		delay   time.Duration
+		star    bool
+		inAgent bool
+	)
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 512*1024))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+
+		switch k {
+		case "user-agent":
+			if !inAgent {
+				star = false
+			}
+			inAgent = true
+			if v == "*" {
+				star = true
+			}
+		case "crawl-delay":
+			inAgent = false
+			if f, err := strconv.ParseFloat(v, 64); err == nil && star {
+				delay = time.Duration(f * float64(time.Second))
+			}
+		default:
+			inAgent = false
+		}
+	}
+	return delay
+}
+
+// limiter is the transport every HTTP helper of the package goes through.
+type limiter struct {
+	base http.RoundTripper
+}
+
+func limited(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &limiter{base: base}
+}
+
+func (a *limiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := limits.acquire(req, a.base)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (a *limiter) CloseIdleConnections() {
+	if c, ok := a.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// releaseBody frees the concurrency slot once the caller is done with the body.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (a *releaseBody) Close() error {
+	err := a.ReadCloser.Close()
+	a.once.Do(a.release)
+	return err
+}
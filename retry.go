@@ -0,0 +1,215 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy describes how the HTTP helpers retry transient failures.
+type RetryPolicy struct {
+	Attempts   int           // total attempts, 1 disables retries
+	Backoff    time.Duration // delay before the second attempt, doubled every next one
+	MaxBackoff time.Duration // upper bound for a single delay, Retry-After included
+	Jitter     float64       // 0..1, random part of every delay
+	Statuses   []int         // status codes worth another attempt
+
+	// Errors reports whether a transport error is worth another attempt.
+	// nil means RetryableError.
+	Errors func(err error) bool
+}
+
+var DefaultRetry = RetryPolicy{
+	Attempts:   3,
+	Backoff:    500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+	Jitter:     0.2,
+	Statuses: []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+var (
+	retryMu sync.RWMutex
+	retry   = DefaultRetry
+)
+
+// SetRetry replaces the policy used by Get, Post, Redirect, DownloadFile and Downloader.
+func SetRetry(p RetryPolicy) {
+	retryMu.Lock()
+	retry = p
+	retryMu.Unlock()
+}
+
+func currentRetry() RetryPolicy {
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	return retry
+}
+
+// HTTPStatusError is returned when a server answers with an unexpected status.
+type HTTPStatusError struct {
+	StatusCode int
+	URL        string
+	Body       []byte // first bytes of the response body
+}
+
+const statusSnippetSize = 512
+
+func (e *HTTPStatusError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("status code is %d", e.StatusCode)
+	}
+	return fmt.Sprintf("status code is %d: %s", e.StatusCode, e.Body)
+}
+
+// statusError reads a snippet of the body and closes it.
+func statusError(resp *http.Response) *HTTPStatusError {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, statusSnippetSize))
+	e := &HTTPStatusError{StatusCode: resp.StatusCode, Body: body}
+	if resp.Request != nil {
+		e.URL = resp.Request.URL.String()
+	}
+	return e
+}
+
+// RetryableError reports whether err looks like a temporary network failure.
+func RetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		err = ue.Err
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+func (p RetryPolicy) retryStatus(code int) bool {
+	for _, x := range p.Statuses {
+		if x == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) retryError(err error) bool {
+	if p.Errors != nil {
+		return p.Errors(err)
+	}
+	return RetryableError(err)
+}
+
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) (d time.Duration) {
+	d = retryAfter
+	if d <= 0 {
+		d = p.Backoff << attempt
+		if d < 0 {
+			d = p.MaxBackoff
+		}
+		if p.Jitter > 0 {
+			d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date forms.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// do sends req until it succeeds, fails permanently or attempts run out.
+// The last response is returned as is, so callers still check the status.
+func (p RetryPolicy) do(c *http.Client, req *http.Request) (*http.Response, error) {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for i := 0; ; i++ {
+		r := req
+		if i > 0 {
+			r = req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return nil, errors.New("request body cannot be replayed")
+				}
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := c.Do(r)
+		if i+1 >= attempts {
+			return resp, err
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if !p.retryError(err) {
+				return nil, err
+			}
+		case p.retryStatus(resp.StatusCode):
+			wait = parseRetryAfter(resp.Header.Get("Retry-After"))
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		t := time.NewTimer(p.delay(i, wait))
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retrier plugs the policy into clients that accept a Do method, like grab.
+type retrier struct {
+	client *http.Client
+	policy RetryPolicy
+}
+
+func (a *retrier) Do(req *http.Request) (*http.Response, error) {
+	return a.policy.do(a.client, req)
+}
@@ -0,0 +1,228 @@
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeOptions configures FileServer.
+type ServeOptions struct {
+	Gzip    bool // compress text-like files on the fly for clients that accept gzip and ask no Range
+	Listing bool // list directories as HTML, or JSON for Accept: application/json and ?json
+}
+
+// ListEntry is one item of a JSON directory listing.
+type ListEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Dir     bool      `json:"dir"`
+}
+
+type fileServer struct {
+	root string
+	opts ServeOptions
+}
+
+// FileServer serves dir with Range and multi-range, ETag and If-None-Match support.
+func FileServer(dir string, opts ...ServeOptions) http.Handler {
+	a := &fileServer{root: dir}
+	if len(opts) > 0 {
+		a.opts = opts[0]
+	}
+	return a
+}
+
+// Serve blocks serving dir on addr, e.g. ":8080".
+func Serve(dir, addr string, opts ...ServeOptions) error {
+	return http.ListenAndServe(addr, FileServer(dir, opts...))
+}
+
+func (a *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// like http.Dir: path.Clean only knows "/", on Windows "/..\..\secret" would get past it
+	if filepath.Separator != '/' && strings.ContainsRune(r.URL.Path, filepath.Separator) ||
+		strings.ContainsRune(r.URL.Path, 0) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	full := filepath.Join(a.root, filepath.FromSlash(name))
+
+	info, err := os.Stat(full)
+	switch {
+	case os.IsNotExist(err):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			u := *r.URL
+			u.Path += "/"
+			http.Redirect(w, r, u.RequestURI(), http.StatusMovedPermanently)
+			return
+		}
+		if index := filepath.Join(full, "index.html"); Exists(index) {
+			full = index
+			if info, err = os.Stat(index); err != nil {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		} else {
+			a.list(w, r, full)
+			return
+		}
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	defer f.Close()
+
+	etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+	ctype := mime.TypeByExtension(filepath.Ext(full))
+
+	if a.opts.Gzip && compressible(ctype) {
+		if r.Header.Get("Range") == "" && acceptsGzip(r) {
+			a.gzip(w, r, f, info, etag, ctype)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+func (a *fileServer) gzip(w http.ResponseWriter, r *http.Request, f io.Reader, info os.FileInfo, etag, ctype string) {
+	etag = strings.TrimSuffix(etag, `"`) + `-gzip"`
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Add("Vary", "Accept-Encoding")
+	h.Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	h.Set("Content-Encoding", "gzip")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	_, _ = io.Copy(gz, f)
+}
+
+func (a *fileServer) list(w http.ResponseWriter, r *http.Request, dir string) {
+	if !a.opts.Listing {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	list := make([]ListEntry, 0, len(files))
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		list = append(list, ListEntry{Name: f.Name(), Size: info.Size(), ModTime: info.ModTime(), Dir: f.IsDir()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Dir != list[j].Dir {
+			return list[i].Dir
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	if _, ok := r.URL.Query()["json"]; ok || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	title := html.EscapeString(path.Clean("/" + r.URL.Path))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!doctype html>\n<title>%s</title>\n<h1>%s</h1>\n<pre>\n<a href=\"../\">../</a>\n", title, title)
+	for _, x := range list {
+		name := x.Name
+		if x.Dir {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\t%s\t%s\n",
+			link.String(), html.EscapeString(name), strconv.FormatInt(x.Size, 10), x.ModTime.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprint(w, "</pre>\n")
+}
+
+func compressible(ctype string) bool {
+	mt, _, _ := mime.ParseMediaType(ctype)
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"),
+		strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+	switch mt {
+	case "application/json", "application/javascript", "application/xml", "application/wasm", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, x := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(x), ";")
+		if strings.TrimSpace(coding) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchETag(header, etag string) bool {
+	for _, x := range strings.Split(header, ",") {
+		x = strings.TrimPrefix(strings.TrimSpace(x), "W/")
+		if x == "*" || x == etag {
+			return true
+		}
+	}
+	return false
+}
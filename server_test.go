@@ -0,0 +1,39 @@
+package file
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileServerUnsafePath(u *testing.T) {
+	__(u)
+
+	dir := u.TempDir()
+	root := filepath.Join(dir, "root")
+	os.MkdirAll(root, 0o755)
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0o644)
+
+	cases := map[string]int{
+		"/a.txt":         http.StatusOK,
+		"/../secret":     http.StatusNotFound,
+		"/a.txt\x00.png": http.StatusBadRequest,
+	}
+	if filepath.Separator != '/' {
+		cases[`/..\secret`] = http.StatusBadRequest
+	}
+
+	h := FileServer(root)
+	for p, code := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = p
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != code || w.Body.String() == "secret" {
+			u.Errorf("%q: got %d %q, want %d", p, w.Code, w.Body.String(), code)
+		}
+	}
+}
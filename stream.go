@@ -0,0 +1,182 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// GetTo streams the body of link into w, decoding gzip, deflate and br on the way.
+// progress counts bytes received from the wire.
+func GetTo(link string, w io.Writer, progress ...func(now, total, percent int)) (n int64, err error) {
+	p := func(now, total, percent int) {}
+	if len(progress) > 0 && progress[0] != nil {
+		p = progress[0]
+	}
+
+	if path, ok := localPath(link); ok {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		counter := &progressReader{r: f, total: Size(path), report: p}
+		n, err = io.CopyBuffer(w, counter, make([]byte, 32*1024))
+		counter.flush()
+		return n, err
+	}
+	if isDataURL(link) {
+		body, _, err := ParseDataURL(link)
+		if err != nil {
+			return 0, err
+		}
+		p(len(body), len(body), 100)
+		return io.Copy(w, bytes.NewReader(body))
+	}
+
+	c, err := httpclient()
+	if err != nil {
+		return
+	}
+	c.Timeout = 0
+
+	resp, err := fetch(c, link, map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	counter := &progressReader{r: resp.Body, total: resp.ContentLength, report: p}
+
+	body, err := decodeBody(counter, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	n, err = io.CopyBuffer(w, body, make([]byte, 32*1024))
+	counter.flush()
+	return
+}
+
+// GetToFile writes link into filename through a temporary file next to it.
+func GetToFile(link, filename string, progress ...func(now, total, percent int)) (err error) {
+	dir, name, full := Path(filename)
+	if err = os.MkdirAll(dir, 0o777); err != nil {
+		return
+	}
+
+	f, err := os.CreateTemp(dir, name+".*.part")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = GetTo(link, f, progress...); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	if err = os.Chmod(f.Name(), 0o666); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), full)
+}
+
+// decodeBody unwraps Content-Encoding, last applied coding first.
+func decodeBody(r io.Reader, encoding string) (io.ReadCloser, error) {
+	var closers []io.Closer
+	codings := strings.Split(encoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		switch c := strings.ToLower(strings.TrimSpace(codings[i])); c {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			closers = append(closers, gz)
+			r = gz
+		case "deflate":
+			// deflate is zlib by the spec, some servers send raw deflate anyway
+			br := bufio.NewReader(r)
+			head, _ := br.Peek(2)
+			if len(head) == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+				z, err := zlib.NewReader(br)
+				if err != nil {
+					return nil, err
+				}
+				closers = append(closers, z)
+				r = z
+			} else {
+				fl := flate.NewReader(br)
+				closers = append(closers, fl)
+				r = fl
+			}
+		case "br":
+			r = brotli.NewReader(r)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", c)
+		}
+	}
+	return &decodedBody{Reader: r, closers: closers}, nil
+}
+
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (a *decodedBody) Close() (err error) {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if e := a.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// progressReader reports read bytes at most twice a second.
+type progressReader struct {
+	r      io.Reader
+	now    int64
+	total  int64
+	last   time.Time
+	report func(now, total, percent int)
+}
+
+func (a *progressReader) Read(p []byte) (n int, err error) {
+	n, err = a.r.Read(p)
+	a.now += int64(n)
+	if time.Since(a.last) >= 500*time.Millisecond {
+		a.flush()
+	}
+	return
+}
+
+func (a *progressReader) flush() {
+	a.last = time.Now()
+	total, percent := a.total, 0
+	if total < 0 {
+		total = 0
+	}
+	if total > 0 {
+		percent = int(100 * a.now / total)
+	}
+	a.report(int(a.now), int(total), percent)
+}
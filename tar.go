@@ -0,0 +1,241 @@
+package file
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tar packs root like ZipDir into a tar, compressed by the extension of to:
+// .tar.gz or .tgz, .tar.zst, .tar.lz4, .tar.br, .tar.xz, a plain tar for any other name.
+// ZipOptions.Methods has no meaning for tar.
+func Tar(to, root string, opts ...ZipOptions) error {
+	var o ZipOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	var codec *Codec
+	if strings.HasSuffix(to, ".tgz") {
+		c, _ := GetCodec("gzip")
+		codec = &c
+	} else if c, ok := codecByExt(filepath.Ext(to)); ok {
+		codec = &c
+	}
+
+	return writeFile(to, func(w io.Writer) (err error) {
+		if codec != nil {
+			if codec.Writer == nil {
+				return fmt.Errorf("tar: %s can only be decompressed", codec.Name)
+			}
+			cw, err := codec.Writer(w, 0)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cerr := cw.Close(); err == nil {
+					err = cerr
+				}
+			}()
+			w = cw
+		}
+
+		tw := tar.NewWriter(w)
+		err = walkArchive(to, root, o, func(name, rel string, info fs.FileInfo) error {
+			return addTarEntry(tw, name, rel, info)
+		})
+		if err != nil {
+			tw.Close()
+			return err
+		}
+		return tw.Close()
+	})
+}
+
+func addTarEntry(tw *tar.Writer, name, rel string, info fs.FileInfo) error {
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(name)
+		if err != nil {
+			return err
+		}
+		link = filepath.ToSlash(target)
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	header.Format = tar.FormatPAX
+	header.Uname, header.Gname = "", ""
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Untar safely extracts a tar, plain or compressed with any registered codec, into dir.
+// The codec is recognized by magic bytes, or by the extension for brotli's .tar.br.
+// Progress gets 0 as the total, a tar has no index.
+func Untar(archive, dir string, opts ...UnzipOptions) error {
+	var o UnzipOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return untar(f, filepath.Ext(archive), dir, o)
+}
+
+// untar reads a tar from r, ext is the codec extension tried when nothing is sniffed.
+func untar(r io.Reader, ext, dir string, o UnzipOptions) error {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	r = br
+	c, ok := sniffCodec(head)
+	if !ok && !isTar(head) {
+		if c, ok = codecByExt(ext); !ok {
+			return &UnknownFormatError{Head: append([]byte(nil), head[:min(len(head), 16)]...)}
+		}
+	}
+	if ok {
+		if c.Name == "zip" {
+			return errors.New("tar: archive is a zip, use UnzipTo or Extract")
+		}
+		cr, err := c.Reader(br)
+		if err != nil {
+			return err
+		}
+		defer cr.Close()
+		r = cr
+	}
+
+	x := newExtractor(dir, o)
+	tr := tar.NewReader(r)
+	for done := 1; ; done++ {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := x.tarEntry(h, tr); err != nil {
+			return fmt.Errorf("%s: %w", h.Name, err)
+		}
+		if o.Progress != nil {
+			o.Progress(h.Name, done, 0)
+		}
+	}
+	return x.finish()
+}
+
+func (x *extractor) tarEntry(h *tar.Header, r io.Reader) error {
+	mode := h.FileInfo().Mode()
+	switch h.Typeflag {
+	case tar.TypeDir:
+		return x.dir(h.Name, mode, h.ModTime)
+	case tar.TypeReg:
+		return x.file(h.Name, mode, h.ModTime, r)
+	case tar.TypeSymlink:
+		return x.symlink(h.Name, h.Linkname)
+	case tar.TypeLink:
+		if err := x.next(); err != nil {
+			return err
+		}
+		p, err := x.path(h.Name)
+		if err != nil {
+			return err
+		}
+		target, err := x.path(h.Linkname)
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(p)
+		return os.Link(target, p)
+	}
+	// devices, fifos and pax records are not extracted
+	return nil
+}
+
+// isTar looks for the ustar magic or a valid header checksum of an old v7 tar.
+func isTar(head []byte) bool {
+	if len(head) < 512 {
+		return false
+	}
+	if bytes.HasPrefix(head[257:], []byte("ustar")) {
+		return true
+	}
+
+	var sum int64
+	for i, b := range head[:512] {
+		if i >= 148 && i < 156 {
+			b = ' '
+		}
+		sum += int64(b)
+	}
+	var want int64
+	_, err := fmt.Sscanf(strings.Trim(string(head[148:156]), " \x00"), "%o", &want)
+	return err == nil && sum == want
+}
+
+// Extract safely unpacks a zip, tar, tar.gz, tar.zst, tar.lz4 or another compressed tar into dir,
+// the format is recognized by content; only .tar.br, which can't be, goes by name.
+func Extract(archive, dir string, opts ...UnzipOptions) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(f, head); err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if bytes.Equal(head, []byte("PK\x03\x04")) || bytes.Equal(head, []byte("PK\x05\x06")) {
+		f.Close()
+		return UnzipTo(archive, dir, opts...)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var o UnzipOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return untar(f, filepath.Ext(archive), dir, o)
+}
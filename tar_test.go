@@ -0,0 +1,39 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarRoundTrip(u *testing.T) {
+	__(u)
+
+	src := filepath.Join(u.TempDir(), "src")
+	files := map[string]string{"a.txt": "alpha", "sub/b.txt": "beta", "sub/deeper/c.txt": "gamma"}
+	for name, body := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		os.WriteFile(p, []byte(body), 0o644)
+	}
+
+	for _, suffix := range []string{".tar", ".tar.gz", ".tgz", ".tar.zst", ".tar.lz4", ".tar.br", ".tar.xz"} {
+		archive := filepath.Join(u.TempDir(), "x"+suffix)
+		if err := Tar(archive, src); err != nil {
+			u.Fatalf("%s: %v", suffix, err)
+		}
+
+		for name, extract := range map[string]func(string, string, ...UnzipOptions) error{"Untar": Untar, "Extract": Extract} {
+			out := u.TempDir()
+			if err := extract(archive, out); err != nil {
+				u.Fatalf("%s %s: %v", name, suffix, err)
+			}
+			for rel, body := range files {
+				b, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(rel)))
+				if err != nil || string(b) != body {
+					u.Errorf("%s %s: %s: %v %q", name, suffix, rel, err, b)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package file
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the connections shared by the HTTP helpers.
+type TransportOptions struct {
+	MaxIdleConns        int // over all hosts, 0 = unlimited
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int // 0 = unlimited
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	KeepAlive           time.Duration
+	TLSHandshakeTimeout time.Duration
+	DisableHTTP2        bool
+	TLS                 *tls.Config // nil = system defaults
+}
+
+var DefaultTransport = TransportOptions{
+	MaxIdleConns:        256,
+	MaxIdleConnsPerHost: 32,
+	IdleConnTimeout:     90 * time.Second,
+	DialTimeout:         10 * time.Second,
+	KeepAlive:           30 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+}
+
+// transports are shared per proxy and TLS config, so keep-alive
+// connections and TLS sessions survive between calls. Configs are told apart
+// by pointer: a caller building a fresh *tls.Config per call gets fresh
+// connections, and at most maxTransports are kept, the least recently used
+// one is dropped first.
+var transports = &transportCache{
+	options: DefaultTransport,
+	list:    make(map[transportKey]*sharedTransport),
+}
+
+type transportKey struct {
+	proxy string
+	tls   *tls.Config
+}
+
+const maxTransports = 32
+
+type sharedTransport struct {
+	base *http.Transport
+	rt   http.RoundTripper
+	used uint64 // tick of the last lookup
+}
+
+type transportCache struct {
+	sync.Mutex
+	options TransportOptions
+	list    map[transportKey]*sharedTransport
+	tick    uint64
+}
+
+// SetTransport replaces the options; idle connections of the old transports are closed.
+func SetTransport(o TransportOptions) {
+	transports.Lock()
+	old := transports.list
+	transports.options = o
+	transports.list = make(map[transportKey]*sharedTransport)
+	transports.Unlock()
+
+	for _, t := range old {
+		t.base.CloseIdleConnections()
+	}
+}
+
+// CloseIdleConnections drops the idle keep-alive connections of every shared transport.
+func CloseIdleConnections() {
+	transports.Lock()
+	defer transports.Unlock()
+	for _, t := range transports.list {
+		t.base.CloseIdleConnections()
+	}
+}
+
+// transport returns the shared round tripper of the HTTP helpers.
+// An explicit proxy wins over the package pool.
+func transport(proxy ...string) (http.RoundTripper, error) {
+	return transportTLS(nil, proxy...)
+}
+
+// transportTLS is transport with a TLS config other than TransportOptions.TLS.
+func transportTLS(cfg *tls.Config, proxy ...string) (http.RoundTripper, error) {
+	key := transportKey{tls: cfg}
+	if len(proxy) > 0 && proxy[0] != "" {
+		u, err := ParseProxy(proxy[0])
+		if err != nil {
+			return nil, err
+		}
+		key.proxy = u.String()
+	}
+
+	transports.Lock()
+	defer transports.Unlock()
+
+	transports.tick++
+	if t := transports.list[key]; t != nil {
+		t.used = transports.tick
+		return t.rt, nil
+	}
+
+	o := transports.options
+	t := &http.Transport{
+		Proxy: defaultProxy,
+		DialContext: (&net.Dialer{
+			Timeout:   o.DialTimeout,
+			KeepAlive: o.KeepAlive,
+		}).DialContext,
+		MaxIdleConns:        o.MaxIdleConns,
+		MaxIdleConnsPerHost: o.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     o.MaxConnsPerHost,
+		IdleConnTimeout:     o.IdleConnTimeout,
+		TLSHandshakeTimeout: o.TLSHandshakeTimeout,
+		ForceAttemptHTTP2:   !o.DisableHTTP2,
+		TLSClientConfig:     &tls.Config{},
+	}
+	if key.proxy != "" {
+		u, _ := ParseProxy(key.proxy)
+		t.Proxy = http.ProxyURL(u)
+	}
+	if cfg == nil {
+		cfg = o.TLS
+	}
+	if cfg != nil {
+		t.TLSClientConfig = cfg.Clone()
+	}
+	if t.TLSClientConfig.ClientSessionCache == nil {
+		t.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	if o.DisableHTTP2 {
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	if len(transports.list) >= maxTransports {
+		transports.evict()
+	}
+	shared := &sharedTransport{base: t, rt: &recorder{base: limited(t)}, used: transports.tick}
+	transports.list[key] = shared
+	return shared.rt, nil
+}
+
+// evict drops the least recently used transport, requests still running on it finish.
+func (a *transportCache) evict() {
+	var oldest transportKey
+	var found *sharedTransport
+	for k, t := range a.list {
+		if found == nil || t.used < found.used {
+			oldest, found = k, t
+		}
+	}
+	if found != nil {
+		delete(a.list, oldest)
+		found.base.CloseIdleConnections()
+	}
+}
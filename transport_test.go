@@ -0,0 +1,28 @@
+package file
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestTransportCacheBound(u *testing.T) {
+	__(u)
+	defer SetTransport(DefaultTransport)
+
+	keep := &tls.Config{}
+	first, _ := transportTLS(keep)
+	for i := 0; i < 3*maxTransports; i++ {
+		transportTLS(&tls.Config{})
+		transportTLS(keep)
+	}
+
+	transports.Lock()
+	n := len(transports.list)
+	transports.Unlock()
+	if n > maxTransports {
+		u.Fatalf("%d transports cached, max %d", n, maxTransports)
+	}
+	if rt, _ := transportTLS(keep); rt != first {
+		u.Fatal("a config in use lost its transport")
+	}
+}
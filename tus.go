@@ -0,0 +1,292 @@
+package file
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tusVersion = "1.0.0"
+
+// Tus uploads a local file with the tus 1.0 resumable upload protocol.
+type Tus struct {
+	endpoint string
+	filename string
+	upload   string // upload URL, known after creation or Resume
+	chunk    int64
+	metadata map[string]string
+	header   map[string]string
+	proxy    string
+	retry    RetryPolicy
+	client   *http.Client
+	progress func(now, total int, percent float64)
+}
+
+// TusUpload prepares the upload of filename to a tus endpoint; nothing is sent until Start.
+func TusUpload(endpoint, filename string) *Tus {
+	return &Tus{
+		endpoint: endpoint,
+		filename: filename,
+		chunk:    8 << 20,
+		metadata: map[string]string{"filename": Filename(filename)},
+		header:   make(map[string]string),
+		retry:    currentRetry(),
+		progress: func(now, total int, percent float64) {},
+	}
+}
+
+// Chunk sets the size of one PATCH request.
+func (a *Tus) Chunk(size int64) *Tus {
+	if size > 0 {
+		a.chunk = size
+	}
+	return a
+}
+
+func (a *Tus) Metadata(k, v string) *Tus {
+	a.metadata[k] = v
+	return a
+}
+
+func (a *Tus) Header(k, v string) *Tus {
+	a.header[k] = v
+	return a
+}
+
+func (a *Tus) Proxy(proxy string) *Tus {
+	a.proxy = proxy
+	return a
+}
+
+// Retry sets how many failed requests in a row are tolerated and the pause between them.
+func (a *Tus) Retry(p RetryPolicy) *Tus {
+	a.retry = p
+	return a
+}
+
+// Resume continues an upload created earlier instead of creating a new one.
+func (a *Tus) Resume(uploadURL string) *Tus {
+	a.upload = uploadURL
+	return a
+}
+
+// URL is the upload URL; keep it to Resume after a crash.
+func (a *Tus) URL() string {
+	return a.upload
+}
+
+func (a *Tus) Start(progress ...func(now, total int, percent float64)) (err error) {
+	if len(progress) > 0 && progress[0] != nil {
+		a.progress = progress[0]
+	}
+
+	c, err := httpclient(a.proxy)
+	if err != nil {
+		return
+	}
+	c.Timeout = 0
+	a.client = c
+
+	f, err := os.Open(a.filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	size := info.Size()
+
+	if a.upload == "" {
+		if err = a.create(size); err != nil {
+			return
+		}
+	}
+
+	offset, err := a.offset()
+	if err != nil {
+		return
+	}
+
+	failures := 0
+	for offset < size {
+		a.report(offset, size)
+
+		next, err := a.patch(f, offset, size)
+		if err == nil {
+			offset, failures = next, 0
+			continue
+		}
+
+		// ask the server what it got before sending the rest
+		for {
+			failures++
+			if failures >= max(a.retry.Attempts, 1) || !a.retryable(err) {
+				return err
+			}
+			time.Sleep(a.retry.delay(failures-1, 0))
+
+			if offset, err = a.offset(); err == nil {
+				break
+			}
+		}
+	}
+
+	a.report(offset, size)
+	return nil
+}
+
+// Terminate deletes the upload on the server.
+func (a *Tus) Terminate() error {
+	if a.upload == "" {
+		return errors.New("tus: upload not created")
+	}
+	if a.client == nil {
+		c, err := httpclient(a.proxy)
+		if err != nil {
+			return err
+		}
+		a.client = c
+	}
+
+	resp, err := a.do(http.MethodDelete, a.upload, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (a *Tus) report(now, total int64) {
+	percent := 100.0
+	if total > 0 {
+		percent = math.Floor(float64(now) / float64(total) * 100)
+	}
+	a.progress(int(now), int(total), percent)
+}
+
+func (a *Tus) retryable(err error) bool {
+	var se *HTTPStatusError
+	if errors.As(err, &se) {
+		return a.retry.retryStatus(se.StatusCode) || se.StatusCode == http.StatusConflict || se.StatusCode == http.StatusLocked
+	}
+	return a.retry.retryError(err)
+}
+
+func (a *Tus) create(size int64) error {
+	h := map[string]string{
+		"Upload-Length":   strconv.FormatInt(size, 10),
+		"Upload-Metadata": tusMetadata(a.metadata),
+	}
+
+	var resp *http.Response
+	var err error
+	for i := 0; ; i++ {
+		resp, err = a.do(http.MethodPost, a.endpoint, h, nil, 0)
+		if err == nil || i+1 >= max(a.retry.Attempts, 1) || !a.retryable(err) {
+			break
+		}
+		time.Sleep(a.retry.delay(i, 0))
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	loc, err := resp.Location()
+	if err != nil {
+		return fmt.Errorf("tus: creation without Location: %w", err)
+	}
+	a.upload = loc.String()
+	return nil
+}
+
+func (a *Tus) offset() (int64, error) {
+	resp, err := a.do(http.MethodHead, a.upload, nil, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+}
+
+func (a *Tus) patch(f *os.File, offset, size int64) (int64, error) {
+	n := min(a.chunk, size-offset)
+	h := map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": strconv.FormatInt(offset, 10),
+	}
+
+	body := &progressReader{
+		r:     io.NewSectionReader(f, offset, n),
+		total: size,
+		now:   offset,
+		last:  time.Now(),
+		report: func(now, total, _ int) {
+			a.report(int64(now), int64(total))
+		},
+	}
+	resp, err := a.do(http.MethodPatch, a.upload, h, body, n)
+	if err != nil {
+		return offset, err
+	}
+	resp.Body.Close()
+
+	next, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return offset, fmt.Errorf("tus: bad Upload-Offset: %w", err)
+	}
+	return next, nil
+}
+
+// do sends one tus request and turns unexpected statuses into *HTTPStatusError.
+func (a *Tus) do(method, link string, header map[string]string, body io.Reader, length int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, link, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range a.header {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	currentProfile().apply(req.Header)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+// tusMetadata encodes "key base64(value)" pairs in a stable order.
+func tusMetadata(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := make([]string, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k+" "+base64.StdEncoding.EncodeToString([]byte(m[k])))
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,190 @@
+package file
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TusInfo is stored next to every upload of TusHandler.
+type TusInfo struct {
+	Length   int64             `json:"length"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type tusServer struct {
+	mu      sync.Mutex
+	busy    map[string]bool // uploads with a PATCH or DELETE in progress
+	dir     string
+	maxSize int64
+}
+
+// TusHandler is a small tus 1.0 server storing uploads in dir as <id> and <id>.info.
+// Supports creation and termination; maxSize 0 means no limit.
+// A PATCH or DELETE of an upload still being written gets 423 Locked, HEAD always answers.
+func TusHandler(dir string, maxSize int64) http.Handler {
+	return &tusServer{dir: dir, maxSize: maxSize, busy: make(map[string]bool)}
+}
+
+func (a *tusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Tus-Resumable", tusVersion)
+
+	if r.Method == http.MethodOptions {
+		h.Set("Tus-Version", tusVersion)
+		h.Set("Tus-Extension", "creation,termination")
+		if a.maxSize > 0 {
+			h.Set("Tus-Max-Size", strconv.FormatInt(a.maxSize, 10))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		h.Set("Tus-Version", tusVersion)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		a.create(w, r)
+		return
+	}
+
+	id := path.Base(r.URL.Path)
+	if id == "" || id == "/" || id == "." || strings.HasSuffix(id, ".info") {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, ok := a.info(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data := filepath.Join(a.dir, id)
+
+	// HEAD never waits, a resuming client asks it while its old PATCH may still hang
+	if r.Method == http.MethodPatch || r.Method == http.MethodDelete {
+		if !a.lock(id) {
+			w.WriteHeader(http.StatusLocked)
+			return
+		}
+		defer a.unlock(id)
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		h.Set("Upload-Offset", strconv.FormatInt(Size(data), 10))
+		h.Set("Upload-Length", strconv.FormatInt(info.Length, 10))
+		h.Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodPatch:
+		a.patch(w, r, data, info)
+
+	case http.MethodDelete:
+		_ = os.Remove(data)
+		_ = os.Remove(data + ".info")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.Set("Allow", "OPTIONS, POST, HEAD, PATCH, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *tusServer) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "bad Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if a.maxSize > 0 && length > a.maxSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	info := TusInfo{Length: length, Metadata: make(map[string]string)}
+	for _, pair := range strings.Split(r.Header.Get("Upload-Metadata"), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if k == "" {
+			continue
+		}
+		info.Metadata[k] = string(Unbase64(strings.TrimRight(v, "=")))
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := hex.EncodeToString(b)
+
+	body, _ := json.Marshal(info)
+	if err := Save(filepath.Join(a.dir, id+".info"), body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := Rewrite(filepath.Join(a.dir, id), nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, id))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (a *tusServer) patch(w http.ResponseWriter, r *http.Request, data string, info TusInfo) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != Size(data) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	f, err := os.OpenFile(data, os.O_WRONLY|os.O_APPEND, 0o666)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// whatever arrived before a broken connection stays, the client asks HEAD and resumes
+	n, _ := io.Copy(f, io.LimitReader(r.Body, info.Length-offset))
+	f.Close()
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset+n, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *tusServer) lock(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.busy[id] {
+		return false
+	}
+	a.busy[id] = true
+	return true
+}
+
+func (a *tusServer) unlock(id string) {
+	a.mu.Lock()
+	delete(a.busy, id)
+	a.mu.Unlock()
+}
+
+func (a *tusServer) info(id string) (info TusInfo, ok bool) {
+	return info, LoadJson(filepath.Join(a.dir, id+".info"), &info) == nil
+}
@@ -0,0 +1,98 @@
+package file
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tusRequest(u *testing.T, method, link string, body io.Reader, header map[string]string) *http.Response {
+	req, _ := http.NewRequest(method, link, body)
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	c := &http.Client{Timeout: 2 * time.Second}
+	resp, err := c.Do(req)
+	if err != nil {
+		u.Fatal(method, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestTusUpload(u *testing.T) {
+	__(u)
+
+	dir := u.TempDir()
+	srv := httptest.NewServer(TusHandler(dir, 0))
+	defer srv.Close()
+
+	data := bytes.Repeat([]byte("tus "), 10000)
+	src := filepath.Join(u.TempDir(), "src.bin")
+	os.WriteFile(src, data, 0o644)
+
+	t := TusUpload(srv.URL+"/files/", src).Chunk(7000)
+	if err := t.Start(); err != nil {
+		u.Fatal(err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, filepath.Base(t.URL())))
+	if !bytes.Equal(got, data) {
+		u.Fatal("uploaded data differs")
+	}
+}
+
+func TestTusStalledPatch(u *testing.T) {
+	__(u)
+
+	dir := u.TempDir()
+	srv := httptest.NewServer(TusHandler(dir, 0))
+	defer srv.Close()
+
+	create := func() string {
+		resp := tusRequest(u, http.MethodPost, srv.URL+"/files/", nil, map[string]string{"Upload-Length": "10"})
+		return srv.URL + resp.Header.Get("Location")
+	}
+	a, b := create(), create()
+
+	// a client that sends a part and then stalls
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go func() {
+		req, _ := http.NewRequest(http.MethodPatch, a, pr)
+		req.Header.Set("Tus-Resumable", tusVersion)
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", "0")
+		req.ContentLength = 10
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	pw.Write([]byte("abc"))
+	for i := 0; i < 100 && Size(filepath.Join(dir, filepath.Base(a))) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp := tusRequest(u, http.MethodHead, a, nil, nil)
+	if resp.Header.Get("Upload-Offset") != "3" {
+		u.Fatal("HEAD of the stalled upload", resp.Status, resp.Header.Get("Upload-Offset"))
+	}
+
+	patch := map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": "0"}
+	resp = tusRequest(u, http.MethodPatch, b, strings.NewReader("0123456789"), patch)
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Upload-Offset") != "10" {
+		u.Fatal("PATCH of another upload", resp.Status)
+	}
+
+	patch["Upload-Offset"] = "3"
+	resp = tusRequest(u, http.MethodPatch, a, strings.NewReader("defghij"), patch)
+	if resp.StatusCode != http.StatusLocked {
+		u.Fatal("second PATCH of the stalled upload", resp.Status)
+	}
+}
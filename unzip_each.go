@@ -0,0 +1,87 @@
+package file
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// StopUnzip returned from an UnzipEach callback ends the walk without an error.
+var StopUnzip = errors.New("stop unzip")
+
+// ZipEntry describes one entry of a zip archive.
+type ZipEntry struct {
+	Name       string
+	Size       int64 // uncompressed
+	Compressed int64
+	CRC32      uint32
+	Modified   time.Time
+	Mode       fs.FileMode
+}
+
+func zipEntryOf(f *zip.File) ZipEntry {
+	return ZipEntry{
+		Name:       f.Name,
+		Size:       int64(f.UncompressedSize64),
+		Compressed: int64(f.CompressedSize64),
+		CRC32:      f.CRC32,
+		Modified:   f.Modified,
+		Mode:       f.Mode(),
+	}
+}
+
+// UnzipEach streams the files of zipfile to f one by one, directories are left out.
+// With match globs only entries whose name or base name matches one are given.
+// r is valid until f returns and the CRC is checked when it is read to the end.
+func UnzipEach(zipfile string, f func(e ZipEntry, r io.Reader) error, match ...string) error {
+	archive, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	return unzipEach(&archive.Reader, f, match)
+}
+
+// UnzipEachFrom is UnzipEach for a zip held in memory or any io.ReaderAt;
+// for a []byte pass bytes.NewReader(b), len(b).
+func UnzipEachFrom(r io.ReaderAt, size int64, f func(e ZipEntry, r io.Reader) error, match ...string) error {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	return unzipEach(archive, f, match)
+}
+
+func unzipEach(archive *zip.Reader, f func(e ZipEntry, r io.Reader) error, match []string) error {
+	if f == nil {
+		return errors.New("callback cannot be nil")
+	}
+
+	for _, af := range archive.File {
+		if af.FileInfo().IsDir() {
+			continue
+		}
+		if len(match) > 0 && !matchGlob(match, af.Name) {
+			continue
+		}
+
+		r, err := af.Open()
+		if err != nil {
+			return err
+		}
+		err = f(zipEntryOf(af), r)
+		r.Close()
+
+		if errors.Is(err, StopUnzip) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,312 @@
+package file
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsafePath   = errors.New("archive entry escapes the destination")
+	ErrTooLarge     = errors.New("archive is larger than allowed")
+	ErrTooManyFiles = errors.New("archive has more entries than allowed")
+)
+
+// UnzipOptions configures UnzipTo; zero limits mean no limit.
+type UnzipOptions struct {
+	MaxSize  int64 // total uncompressed bytes, counted while writing, not taken from headers
+	MaxFiles int   // entries of any kind
+	Progress func(name string, done, total int)
+}
+
+// UnzipTo extracts zipfile into dir restoring modes, mtimes, directories and symlinks.
+// Entries with absolute names, ".." or symlinks pointing outside dir, at dir itself
+// or through other symlinks fail with ErrUnsafePath.
+func UnzipTo(zipfile, dir string, opts ...UnzipOptions) error {
+	var o UnzipOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	archive, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if o.MaxFiles > 0 && len(archive.File) > o.MaxFiles {
+		return ErrTooManyFiles
+	}
+
+	x := newExtractor(dir, o)
+	for i, f := range archive.File {
+		if err := x.zipEntry(f); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if o.Progress != nil {
+			o.Progress(f.Name, i+1, len(archive.File))
+		}
+	}
+	return x.finish()
+}
+
+func (x *extractor) zipEntry(f *zip.File) error {
+	mode := f.Mode()
+	switch {
+	case mode.IsDir():
+		return x.dir(f.Name, mode, f.Modified)
+
+	case mode&fs.ModeSymlink != 0:
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		target, err := io.ReadAll(io.LimitReader(r, 4096))
+		if err != nil {
+			return err
+		}
+		return x.symlink(f.Name, string(target))
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return x.file(f.Name, mode, f.Modified, r)
+}
+
+// extractor writes archive entries under a directory, zip and tar alike.
+type extractor struct {
+	root  string
+	opts  UnzipOptions
+	size  int64
+	count int
+	dirs  []extractedDir // mtimes are set last, files change them
+}
+
+type extractedDir struct {
+	name  string
+	mtime time.Time
+}
+
+func newExtractor(dir string, o UnzipOptions) *extractor {
+	return &extractor{root: filepath.Clean(dir), opts: o}
+}
+
+// path maps an entry name to a file under root.
+func (x *extractor) path(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if name == "" || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrUnsafePath
+	}
+	for _, p := range strings.Split(name, "/") {
+		if p == ".." {
+			return "", ErrUnsafePath
+		}
+	}
+	name = path.Clean(name)
+
+	// entries never go through symlinks made by earlier entries
+	p := x.root
+	parts := strings.Split(name, "/")
+	for _, part := range parts[:len(parts)-1] {
+		p = filepath.Join(p, part)
+		if info, err := os.Lstat(p); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", ErrUnsafePath
+		}
+	}
+	return filepath.Join(x.root, filepath.FromSlash(name)), nil
+}
+
+func (x *extractor) next() error {
+	x.count++
+	if x.opts.MaxFiles > 0 && x.count > x.opts.MaxFiles {
+		return ErrTooManyFiles
+	}
+	return nil
+}
+
+func (x *extractor) dir(name string, mode fs.FileMode, mtime time.Time) error {
+	if err := x.next(); err != nil {
+		return err
+	}
+	p, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p, 0o755); err != nil {
+		return err
+	}
+	if perm := mode.Perm(); perm != 0 {
+		if err := os.Chmod(p, perm|0o700); err != nil {
+			return err
+		}
+	}
+	if !mtime.IsZero() {
+		x.dirs = append(x.dirs, extractedDir{p, mtime})
+	}
+	return nil
+}
+
+func (x *extractor) file(name string, mode fs.FileMode, mtime time.Time, r io.Reader) (err error) {
+	if err := x.next(); err != nil {
+		return err
+	}
+	p, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0o644
+	}
+	_ = os.Remove(p) // don't write through a symlink left there
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if x.opts.MaxSize > 0 {
+		r = io.LimitReader(r, x.opts.MaxSize-x.size+1)
+	}
+	n, err := io.Copy(f, r)
+	x.size += n
+	if err != nil {
+		return err
+	}
+	if x.opts.MaxSize > 0 && x.size > x.opts.MaxSize {
+		return ErrTooLarge
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if !mtime.IsZero() {
+		return os.Chtimes(p, mtime, mtime)
+	}
+	return nil
+}
+
+func (x *extractor) symlink(name, target string) error {
+	if err := x.next(); err != nil {
+		return err
+	}
+	p, err := x.path(name)
+	if err != nil {
+		return err
+	}
+
+	t := filepath.FromSlash(target)
+	if t == "" || filepath.IsAbs(t) || filepath.VolumeName(t) != "" {
+		return ErrUnsafePath
+	}
+
+	// ".." only at the start: in "a/.." a could be a link made later
+	parts := strings.Split(filepath.ToSlash(target), "/")
+	up := true
+	for _, part := range parts {
+		switch part {
+		case "", ".":
+		case "..":
+			if !up {
+				return ErrUnsafePath
+			}
+		default:
+			up = false
+		}
+	}
+
+	// the target must stay inside root, root itself included
+	dir := filepath.Dir(p)
+	resolved := filepath.Join(dir, t)
+	if !within(x.root, resolved) {
+		return ErrUnsafePath
+	}
+
+	// and must not pass through links already extracted
+	cur := dir
+	for _, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			cur = filepath.Dir(cur)
+			continue
+		}
+		cur = filepath.Join(cur, part)
+		if info, err := os.Lstat(cur); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return ErrUnsafePath
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if ok, err := x.resolvesWithin(resolved); err != nil || !ok {
+		return ErrUnsafePath
+	}
+
+	_ = os.Remove(p)
+	return os.Symlink(t, p)
+}
+
+// resolvesWithin follows the links on disk in the existing part of p.
+func (x *extractor) resolvesWithin(p string) (bool, error) {
+	root, err := filepath.EvalSymlinks(x.root)
+	if err != nil {
+		return false, err
+	}
+
+	existing, rest := p, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return false, nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	real, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return false, err
+	}
+	return within(root, filepath.Join(real, rest)), nil
+}
+
+// within reports whether p is strictly under root.
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (x *extractor) finish() error {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		d := x.dirs[i]
+		if err := os.Chtimes(d.name, d.mtime, d.mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
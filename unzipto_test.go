@@ -0,0 +1,165 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	name string
+	body string
+	link bool
+}
+
+func testZip(u *testing.T, entries ...testEntry) string {
+	var b bytes.Buffer
+	z := NewZipBuilder(&b)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.link {
+			h.SetMode(os.ModeSymlink | 0o777)
+		} else {
+			h.SetMode(0o644)
+		}
+		z.AddHeader(h, strings.NewReader(e.body))
+	}
+	if err := z.Close(); err != nil {
+		u.Fatal(err)
+	}
+	name := filepath.Join(u.TempDir(), "test.zip")
+	if err := os.WriteFile(name, b.Bytes(), 0o644); err != nil {
+		u.Fatal(err)
+	}
+	return name
+}
+
+func testTar(u *testing.T, entries ...testEntry) string {
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.link {
+			h.Typeflag, h.Linkname, h.Size = tar.TypeSymlink, e.body, 0
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			u.Fatal(err)
+		}
+		if !e.link {
+			tw.Write([]byte(e.body))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		u.Fatal(err)
+	}
+	name := filepath.Join(u.TempDir(), "test.tar")
+	if err := os.WriteFile(name, b.Bytes(), 0o644); err != nil {
+		u.Fatal(err)
+	}
+	return name
+}
+
+// escaped reports whether any link under dir resolves outside of it.
+func escaped(u *testing.T, dir string) bool {
+	root, _ := filepath.EvalSymlinks(dir)
+	out := false
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil && !within(root, real) {
+			u.Logf("%s resolves to %s", p, real)
+			out = true
+		}
+		return nil
+	})
+	return out
+}
+
+func TestUnzipToSymlinkEscape(u *testing.T) {
+	__(u)
+
+	cases := map[string][]testEntry{
+		"through link": {{name: "s2", body: ".", link: true}, {name: "s1", body: "s2/..", link: true}},
+		"link first":   {{name: "s1", body: "a/s2/../../x", link: true}, {name: "a/s2", body: ".", link: true}},
+		"root":         {{name: "s", body: ".", link: true}},
+		"parent":       {{name: "a/s", body: "../..", link: true}},
+		"absolute":     {{name: "s", body: "/etc", link: true}},
+	}
+	for name, entries := range cases {
+		for _, archive := range []string{testZip(u, entries...), testTar(u, entries...)} {
+			out := filepath.Join(u.TempDir(), "out")
+			err := Extract(archive, out)
+			if !errors.Is(err, ErrUnsafePath) {
+				u.Errorf("%s %s: want ErrUnsafePath, got %v", name, filepath.Base(archive), err)
+			}
+			if escaped(u, out) {
+				u.Errorf("%s %s: link outside of the destination", name, filepath.Base(archive))
+			}
+		}
+	}
+}
+
+func TestUnzipToSymlinkInside(u *testing.T) {
+	__(u)
+
+	archive := testZip(u,
+		testEntry{name: "a/file.txt", body: "x"},
+		testEntry{name: "a/link", body: "file.txt", link: true},
+		testEntry{name: "b/link", body: "../a/file.txt", link: true},
+	)
+	out := filepath.Join(u.TempDir(), "out")
+	if err := UnzipTo(archive, out); err != nil {
+		u.Fatal(err)
+	}
+	for _, name := range []string{"a/link", "b/link"} {
+		body, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(body) != "x" {
+			u.Fatal(name, err, string(body))
+		}
+	}
+}
+
+func TestUnzipToUnsafeNames(u *testing.T) {
+	__(u)
+
+	for _, name := range []string{"../evil", "a/../../evil", "/abs/evil", `..\evil`, `a\..\..\evil`} {
+		for _, archive := range []string{testZip(u, testEntry{name: name, body: "x"}), testTar(u, testEntry{name: name, body: "x"})} {
+			dir := u.TempDir()
+			out := filepath.Join(dir, "out")
+			if err := Extract(archive, out); !errors.Is(err, ErrUnsafePath) {
+				u.Errorf("%q %s: want ErrUnsafePath, got %v", name, filepath.Base(archive), err)
+			}
+			if Exists(filepath.Join(dir, "evil")) {
+				u.Errorf("%q: written outside of the destination", name)
+			}
+		}
+	}
+}
+
+func TestUnzipToLimits(u *testing.T) {
+	__(u)
+
+	entries := []testEntry{
+		{name: "a", body: strings.Repeat("a", 100)},
+		{name: "b", body: strings.Repeat("b", 100)},
+		{name: "c", body: strings.Repeat("c", 100)},
+	}
+	for _, archive := range []string{testZip(u, entries...), testTar(u, entries...)} {
+		if err := Extract(archive, u.TempDir(), UnzipOptions{MaxSize: 250}); !errors.Is(err, ErrTooLarge) {
+			u.Errorf("%s: want ErrTooLarge, got %v", filepath.Base(archive), err)
+		}
+		if err := Extract(archive, u.TempDir(), UnzipOptions{MaxFiles: 2}); !errors.Is(err, ErrTooManyFiles) {
+			u.Errorf("%s: want ErrTooManyFiles, got %v", filepath.Base(archive), err)
+		}
+		if err := Extract(archive, u.TempDir(), UnzipOptions{MaxSize: 300, MaxFiles: 3}); err != nil {
+			u.Errorf("%s: %v", filepath.Base(archive), err)
+		}
+	}
+}
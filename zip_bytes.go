@@ -0,0 +1,93 @@
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"sort"
+	"time"
+)
+
+// ZipBuilder writes a zip to any writer, a buffer or an http.ResponseWriter.
+// The first error stops the building and is returned by Close.
+type ZipBuilder struct {
+	w   *zip.Writer
+	err error
+}
+
+func NewZipBuilder(w io.Writer) *ZipBuilder {
+	return &ZipBuilder{w: zip.NewWriter(w)}
+}
+
+// Add copies r into the entry name, deflated.
+func (a *ZipBuilder) Add(name string, r io.Reader) *ZipBuilder {
+	return a.add(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}, r)
+}
+
+func (a *ZipBuilder) AddBytes(name string, body []byte) *ZipBuilder {
+	return a.Add(name, bytes.NewReader(body))
+}
+
+// AddHeader adds an entry with full control over method, mtime and mode.
+func (a *ZipBuilder) AddHeader(h *zip.FileHeader, r io.Reader) *ZipBuilder {
+	return a.add(h, r)
+}
+
+func (a *ZipBuilder) add(h *zip.FileHeader, r io.Reader) *ZipBuilder {
+	if a.err != nil {
+		return a
+	}
+	w, err := a.w.CreateHeader(h)
+	if err != nil {
+		a.err = err
+		return a
+	}
+	if r != nil {
+		_, a.err = io.Copy(w, r)
+	}
+	return a
+}
+
+// Close writes the central directory; the underlying writer stays open.
+func (a *ZipBuilder) Close() error {
+	if err := a.w.Close(); a.err == nil {
+		a.err = err
+	}
+	return a.err
+}
+
+// ZipBytes builds a zip in memory, entries in name order.
+func ZipBytes(entries map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	z := NewZipBuilder(&b)
+	for _, name := range names {
+		z.AddBytes(name, entries[name])
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnzipBytes reads the files of a zip held in memory, directories are left out.
+func UnzipBytes(b []byte) (map[string][]byte, error) {
+	res := make(map[string][]byte)
+	err := UnzipEachFrom(bytes.NewReader(b), int64(len(b)), func(e ZipEntry, r io.Reader) error {
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		res[e.Name] = body
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
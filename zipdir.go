@@ -0,0 +1,147 @@
+package file
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ZipOptions configures ZipDir.
+type ZipOptions struct {
+	Base    string            // entry names are relative to it, default root; set to the parent of root to keep root's name
+	Include []string          // globs for files, matched against the relative slash path or the base name; empty = all
+	Exclude []string          // globs for files and directories, an excluded directory is skipped whole
+	Methods map[string]uint16 // extension => zip.Store or zip.Deflate, default Deflate
+}
+
+// ZipDir packs root recursively into to with modes, mtimes, empty directories and symlinks.
+// Directories are written when Include is empty or matches them.
+func ZipDir(to, root string, opts ...ZipOptions) error {
+	var o ZipOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	return writeFile(to, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		err := walkArchive(to, root, o, func(name, rel string, info fs.FileInfo) error {
+			return addZipEntry(zw, name, rel, info, o.Methods)
+		})
+		if err != nil {
+			zw.Close()
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+func addZipEntry(zw *zip.Writer, name, rel string, info fs.FileInfo, methods map[string]uint16) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+
+	switch {
+	case info.IsDir():
+		header.Name += "/"
+		header.Method = zip.Store
+		_, err = zw.CreateHeader(header)
+		return err
+
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(name)
+		if err != nil {
+			return err
+		}
+		header.Method = zip.Store
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, filepath.ToSlash(target))
+		return err
+	}
+
+	header.Method = zip.Deflate
+	if m, ok := methods[strings.ToLower(filepath.Ext(rel))]; ok {
+		header.Method = m
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// walkArchive calls add for every entry of root that passes the options, never for the archive itself.
+// rel is the slash separated name relative to o.Base.
+func walkArchive(archive, root string, o ZipOptions, add func(name, rel string, info fs.FileInfo) error) error {
+	base := o.Base
+	if base == "" {
+		base = root
+	}
+	self, _ := filepath.Abs(archive)
+
+	return filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if abs, _ := filepath.Abs(name); abs == self {
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("zip: %s is outside of base %s", name, base)
+		}
+
+		if matchGlob(o.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(o.Include) > 0 && !matchGlob(o.Include, rel) {
+			return nil
+		}
+
+		info, err := os.Lstat(name)
+		if err != nil {
+			return err
+		}
+		return add(name, rel, info)
+	})
+}
+
+func matchGlob(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package file
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// ZipList returns the entries of zipfile, directories included, without reading any body.
+func ZipList(zipfile string) ([]ZipEntry, error) {
+	archive, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	list := make([]ZipEntry, 0, len(archive.File))
+	for _, f := range archive.File {
+		list = append(list, zipEntryOf(f))
+	}
+	return list, nil
+}
+
+// ZipRead reads one entry of zipfile, the others are not touched.
+func ZipRead(zipfile, name string) ([]byte, error) {
+	archive, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	return fs.ReadFile(archive, name)
+}
+
+// ZipFileSystem is an opened zip seen as an fs.FS, for templates, fs.WalkDir or http.FS.
+// Its files are seekable so http.FileServer can serve them with ranges.
+type ZipFileSystem struct {
+	archive *zip.ReadCloser
+	files   map[string]*zip.File
+}
+
+// ZipFS opens zipfile as a file system; Close it when done.
+func ZipFS(zipfile string) (*ZipFileSystem, error) {
+	archive, err := zip.OpenReader(zipfile)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &ZipFileSystem{archive: archive, files: make(map[string]*zip.File)}
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			a.files[f.Name] = f
+		}
+	}
+	return a, nil
+}
+
+func (a *ZipFileSystem) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := a.files[name]; ok {
+		return &zipFile{f: f}, nil
+	}
+	// directories, also the ones only implied by file names
+	return a.archive.Open(name)
+}
+
+func (a *ZipFileSystem) Close() error {
+	return a.archive.Close()
+}
+
+// zipFile decompresses lazily: Seek only moves the position, Read catches up,
+// reopening the entry when it has to go back.
+type zipFile struct {
+	f   *zip.File
+	r   io.ReadCloser
+	at  int64 // position of r
+	pos int64 // position asked for
+}
+
+func (z *zipFile) Stat() (fs.FileInfo, error) {
+	return z.f.FileInfo(), nil
+}
+
+func (z *zipFile) Read(p []byte) (int, error) {
+	if z.pos >= int64(z.f.UncompressedSize64) {
+		return 0, io.EOF
+	}
+	if z.r == nil || z.pos < z.at {
+		if z.r != nil {
+			z.r.Close()
+		}
+		r, err := z.f.Open()
+		if err != nil {
+			return 0, err
+		}
+		z.r, z.at = r, 0
+	}
+	if z.pos > z.at {
+		n, err := io.CopyN(io.Discard, z.r, z.pos-z.at)
+		z.at += n
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := z.r.Read(p)
+	z.at += int64(n)
+	z.pos = z.at
+	return n, err
+}
+
+func (z *zipFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += z.pos
+	case io.SeekEnd:
+		offset += int64(z.f.UncompressedSize64)
+	default:
+		return 0, errors.New("zip: bad whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("zip: negative position")
+	}
+	z.pos = offset
+	return offset, nil
+}
+
+func (z *zipFile) Close() error {
+	if z.r == nil {
+		return nil
+	}
+	err := z.r.Close()
+	z.r = nil
+	return err
+}
@@ -0,0 +1,191 @@
+package file
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstd encoders and decoders are safe for concurrent EncodeAll/DecodeAll, keep one per level
+var (
+	zstdEncoders sync.Map // level => *zstd.Encoder
+	zstdDecoder  = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+func zstdEncoder(level int) (*zstd.Encoder, error) {
+	if e, ok := zstdEncoders.Load(level); ok {
+		return e.(*zstd.Encoder), nil
+	}
+	var opts []zstd.EOption
+	if level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	e, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := zstdEncoders.LoadOrStore(level, e)
+	return actual.(*zstd.Encoder), nil
+}
+
+// Zstd compresses body, level is on the zstd 1..22 scale, default 3.
+func Zstd(body []byte, level ...int) ([]byte, error) {
+	var l int
+	if len(level) > 0 {
+		l = level[0]
+	}
+	e, err := zstdEncoder(l)
+	if err != nil {
+		return nil, err
+	}
+	return e.EncodeAll(body, nil), nil
+}
+
+func UnZstd(b []byte) ([]byte, error) {
+	d, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return d.DecodeAll(b, nil)
+}
+
+// NewZstdWriter compresses to w; Close flushes but leaves w open.
+func NewZstdWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return NewCompressWriter(w, "zstd", level)
+}
+
+func NewZstdReader(r io.Reader) (io.ReadCloser, error) {
+	c, _ := GetCodec("zstd")
+	return c.Reader(r)
+}
+
+// ZstdDict is a dictionary for compressing many small similar records.
+// Both sides must use the same dictionary.
+type ZstdDict struct {
+	raw []byte
+	id  uint32
+
+	once sync.Once
+	enc  *zstd.Encoder
+	dec  *zstd.Decoder
+	err  error
+}
+
+// TrainZstdDict builds a dictionary of up to size bytes (default 64 KB) from sample records.
+// The history is made of the samples themselves, the most recent ones win when they don't fit.
+func TrainZstdDict(samples [][]byte, size int) (*ZstdDict, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("zstd: no samples")
+	}
+	if size <= 0 {
+		size = 64 << 10
+	}
+
+	var hist []byte
+	seen := make(map[string]bool)
+	for i := len(samples) - 1; i >= 0 && len(hist) < size; i-- {
+		s := samples[i]
+		if len(s) == 0 || seen[string(s)] {
+			continue
+		}
+		seen[string(s)] = true
+		if n := size - len(hist); len(s) > n {
+			s = s[len(s)-n:]
+		}
+		hist = append(append([]byte(nil), s...), hist...)
+	}
+	if len(hist) < 8 {
+		return nil, errors.New("zstd: samples too small for a dictionary")
+	}
+
+	// ids below 32768 are reserved
+	id := crc32.ChecksumIEEE(hist)%(1<<31-32768) + 32768
+
+	raw, err := zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: samples,
+		History:  hist,
+		Offsets:  [3]int{1, 4, 8},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdDict{raw: raw, id: id}, nil
+}
+
+// LoadZstdDict reads a dictionary saved with Save or made by the zstd command line tool.
+func LoadZstdDict(filename string) (*ZstdDict, error) {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewZstdDict(raw)
+}
+
+// NewZstdDict wraps a dictionary in the zstd format.
+func NewZstdDict(raw []byte) (*ZstdDict, error) {
+	info, err := zstd.InspectDictionary(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdDict{raw: raw, id: info.ID()}, nil
+}
+
+func (a *ZstdDict) Save(filename string) error {
+	return os.WriteFile(filename, a.raw, 0o644)
+}
+
+func (a *ZstdDict) Bytes() []byte {
+	return a.raw
+}
+
+func (a *ZstdDict) ID() uint32 {
+	return a.id
+}
+
+func (a *ZstdDict) init() error {
+	a.once.Do(func() {
+		if a.enc, a.err = zstd.NewWriter(nil, zstd.WithEncoderDict(a.raw)); a.err != nil {
+			return
+		}
+		a.dec, a.err = zstd.NewReader(nil, zstd.WithDecoderDicts(a.raw))
+	})
+	return a.err
+}
+
+func (a *ZstdDict) Compress(body []byte) ([]byte, error) {
+	if err := a.init(); err != nil {
+		return nil, err
+	}
+	return a.enc.EncodeAll(body, nil), nil
+}
+
+func (a *ZstdDict) Decompress(b []byte) ([]byte, error) {
+	if err := a.init(); err != nil {
+		return nil, err
+	}
+	return a.dec.DecodeAll(b, nil)
+}
+
+// NewWriter compresses a stream with the dictionary.
+func (a *ZstdDict) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	opts := []zstd.EOption{zstd.WithEncoderDict(a.raw)}
+	if level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	return zstd.NewWriter(w, opts...)
+}
+
+func (a *ZstdDict) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderDicts(a.raw))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}